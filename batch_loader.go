@@ -0,0 +1,204 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultBatchMaxWait = time.Millisecond
+
+// BatchOptions configures the batching of a [BatchLoader].
+type BatchOptions struct {
+	// MaxBatchSize is the maximum number of keys in a batch. The batch is loaded as soon as it is full.
+	//
+	// If MaxBatchSize is not positive, the size of the batches is not limited.
+	MaxBatchSize int
+	// MaxWait is the maximum time that the first key of a batch waits for other keys before the batch is loaded.
+	//
+	// If MaxWait is not positive, 1ms is used.
+	MaxWait time.Duration
+	// Clock is used to wait MaxWait. If Clock implements [Sleeper], the batch waits by calling its Sleep method,
+	// so a fake Clock can complete the wait without taking real time.
+	//
+	// By default, and if Clock doesn't implement Sleeper, the batch waits using a [time.Timer].
+	Clock Clock
+}
+
+// BatchLoader is a [Loader] that coalesces the concurrent loads of distinct keys into one BulkLoader.BulkLoad call,
+// and the concurrent reloads into one BulkLoader.BulkReload call. It is useful when many goroutines call
+// Cache.Get for different missing keys at the same time, and every Loader.Load is a round-trip to the data source.
+//
+// Cache.Get still loads every key exactly once, because the loads of the same key are deduplicated by the cache
+// before they reach BatchLoader.
+//
+// A batch is loaded with the context of the first key in the batch, without its cancellation.
+// A caller whose context is canceled stops waiting for the batch and returns the context's error.
+// The keys that are missing in the map returned by BulkLoader are reported as ErrNotFound, and the extra keys
+// are ignored.
+//
+// BatchLoader is safe for concurrent use and can be shared by several caches.
+type BatchLoader[K comparable, V any] struct {
+	bulkLoader   BulkLoader[K, V]
+	maxBatchSize int
+	maxWait      time.Duration
+	sleeper      Sleeper
+	mutex        sync.Mutex
+	load         *batch[K, V]
+	reload       *batch[K, V]
+}
+
+// NewBatchLoader returns a new [BatchLoader] that loads the batches of keys using bulkLoader.
+func NewBatchLoader[K comparable, V any](bulkLoader BulkLoader[K, V], o BatchOptions) *BatchLoader[K, V] {
+	maxWait := o.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultBatchMaxWait
+	}
+	sleeper, _ := o.Clock.(Sleeper)
+	return &BatchLoader[K, V]{
+		bulkLoader:   bulkLoader,
+		maxBatchSize: o.MaxBatchSize,
+		maxWait:      maxWait,
+		sleeper:      sleeper,
+	}
+}
+
+// Load adds the key to the current batch of loads and waits for the batch to be loaded using BulkLoader.BulkLoad.
+func (bl *BatchLoader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	b := bl.add(ctx, &bl.load, key, zeroValue[V](), false)
+	return b.get(ctx, key)
+}
+
+// Reload adds the key to the current batch of reloads and waits for the batch to be loaded
+// using BulkLoader.BulkReload.
+func (bl *BatchLoader[K, V]) Reload(ctx context.Context, key K, oldValue V) (V, error) {
+	b := bl.add(ctx, &bl.reload, key, oldValue, true)
+	return b.get(ctx, key)
+}
+
+func (bl *BatchLoader[K, V]) add(ctx context.Context, pending **batch[K, V], key K, oldValue V, isReload bool) *batch[K, V] {
+	bl.mutex.Lock()
+	b := *pending
+	if b == nil {
+		b = &batch[K, V]{
+			ctx:      context.WithoutCancel(ctx),
+			index:    make(map[K]struct{}),
+			done:     make(chan struct{}),
+			isReload: isReload,
+		}
+		*pending = b
+		bl.startTimer(pending, b)
+	}
+	if _, ok := b.index[key]; !ok {
+		b.index[key] = struct{}{}
+		b.keys = append(b.keys, key)
+		if isReload {
+			b.oldValues = append(b.oldValues, oldValue)
+		}
+	}
+	isFull := bl.maxBatchSize > 0 && len(b.keys) >= bl.maxBatchSize
+	if isFull {
+		*pending = nil
+		b.stopTimer()
+	}
+	bl.mutex.Unlock()
+
+	if isFull {
+		// the caller that filled the batch waits for it like the others, so it can stop waiting on cancellation.
+		go b.run(bl.bulkLoader)
+	}
+	return b
+}
+
+// startTimer flushes the batch after maxWait unless it's stopped earlier.
+func (bl *BatchLoader[K, V]) startTimer(pending **batch[K, V], b *batch[K, V]) {
+	if bl.sleeper == nil {
+		timer := time.AfterFunc(bl.maxWait, func() {
+			bl.flush(pending, b)
+		})
+		b.stopTimer = func() {
+			timer.Stop()
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.stopTimer = cancel
+	go func() {
+		if err := bl.sleeper.Sleep(ctx, bl.maxWait); err == nil {
+			bl.flush(pending, b)
+		}
+	}()
+}
+
+// flush loads the batch if it wasn't loaded because it became full.
+func (bl *BatchLoader[K, V]) flush(pending **batch[K, V], b *batch[K, V]) {
+	bl.mutex.Lock()
+	if *pending != b {
+		bl.mutex.Unlock()
+		return
+	}
+	*pending = nil
+	bl.mutex.Unlock()
+
+	b.run(bl.bulkLoader)
+}
+
+type batch[K comparable, V any] struct {
+	ctx       context.Context
+	keys      []K
+	oldValues []V
+	index     map[K]struct{}
+	stopTimer func()
+	done      chan struct{}
+	results   map[K]V
+	err       error
+	isReload  bool
+}
+
+func (b *batch[K, V]) run(bulkLoader BulkLoader[K, V]) {
+	defer func() {
+		if r := recover(); r != nil {
+			// the panic is rethrown by the cache in the goroutines waiting for the keys.
+			b.err = newPanicError(r)
+		}
+		close(b.done)
+	}()
+
+	if b.isReload {
+		b.results, b.err = bulkLoader.BulkReload(b.ctx, b.keys, b.oldValues)
+	} else {
+		b.results, b.err = bulkLoader.BulkLoad(b.ctx, b.keys)
+	}
+}
+
+func (b *batch[K, V]) get(ctx context.Context, key K) (V, error) {
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return zeroValue[V](), ctx.Err()
+	}
+
+	if b.err != nil {
+		return zeroValue[V](), b.err
+	}
+	v, ok := b.results[key]
+	if !ok {
+		return zeroValue[V](), ErrNotFound
+	}
+	return v, nil
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingBulkLoader struct {
+	mutex     sync.Mutex
+	loads     [][]int
+	reloads   [][]int
+	oldValues [][]int
+	err       error
+}
+
+func (tbl *recordingBulkLoader) BulkLoad(ctx context.Context, keys []int) (map[int]int, error) {
+	tbl.mutex.Lock()
+	defer tbl.mutex.Unlock()
+
+	tbl.loads = append(tbl.loads, slices.Sorted(slices.Values(keys)))
+	return tbl.result(keys)
+}
+
+func (tbl *recordingBulkLoader) BulkReload(ctx context.Context, keys []int, oldValues []int) (map[int]int, error) {
+	tbl.mutex.Lock()
+	defer tbl.mutex.Unlock()
+
+	tbl.reloads = append(tbl.reloads, slices.Clone(keys))
+	tbl.oldValues = append(tbl.oldValues, slices.Clone(oldValues))
+	return tbl.result(keys)
+}
+
+func (tbl *recordingBulkLoader) result(keys []int) (map[int]int, error) {
+	if tbl.err != nil {
+		return nil, tbl.err
+	}
+	result := make(map[int]int, len(keys))
+	for _, k := range keys {
+		// the negative keys are not found.
+		if k >= 0 {
+			result[k] = k + 100
+		}
+	}
+	return result, nil
+}
+
+func TestBatchLoader_Load(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tbl := &recordingBulkLoader{}
+	loader := NewBatchLoader[int, int](tbl, BatchOptions{
+		MaxBatchSize: 10,
+		MaxWait:      time.Hour,
+	})
+	c := Must[int, int](&Options[int, int]{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				v, err := c.Get(ctx, i, loader)
+				require.NoError(t, err)
+				require.Equal(t, i+100, v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}}, tbl.loads)
+}
+
+func TestBatchLoader_MaxWait(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tbl := &recordingBulkLoader{}
+	loader := NewBatchLoader[int, int](tbl, BatchOptions{})
+	c := Must[int, int](&Options[int, int]{})
+
+	v, err := c.Get(ctx, 1, loader)
+	require.NoError(t, err)
+	require.Equal(t, 101, v)
+
+	_, err = c.Get(ctx, -1, loader)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, [][]int{{1}, {-1}}, tbl.loads)
+
+	tbl.err = errors.New("failed")
+	_, err = c.Get(ctx, 2, loader)
+	require.ErrorIs(t, err, tbl.err)
+	_, ok := c.GetIfPresent(2)
+	require.False(t, ok)
+}
+
+func TestBatchLoader_UserClock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sc := &sleepingClock{}
+	tbl := &recordingBulkLoader{}
+	// the wait of an hour doesn't take real time.
+	loader := NewBatchLoader[int, int](tbl, BatchOptions{
+		MaxWait: time.Hour,
+		Clock:   sc,
+	})
+	c := Must[int, int](&Options[int, int]{})
+
+	v, err := c.Get(ctx, 1, loader)
+	require.NoError(t, err)
+	require.Equal(t, 101, v)
+	require.Equal(t, [][]int{{1}}, tbl.loads)
+	require.Equal(t, []time.Duration{time.Hour}, sc.sleeps)
+}
+
+func TestBatchLoader_Reload(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tbl := &recordingBulkLoader{}
+	loader := NewBatchLoader[int, int](tbl, BatchOptions{
+		MaxBatchSize: 2,
+		MaxWait:      time.Hour,
+	})
+	c := Must(&Options[int, int]{
+		RefreshCalculator: RefreshWriting[int, int](time.Hour),
+	})
+	c.Set(1, 1)
+	c.Set(2, 2)
+
+	ch1 := c.Refresh(ctx, 1, loader)
+	ch2 := c.Refresh(ctx, 2, loader)
+	require.Equal(t, RefreshResult[int, int]{Key: 1, Value: 101}, <-ch1)
+	require.Equal(t, RefreshResult[int, int]{Key: 2, Value: 102}, <-ch2)
+
+	require.Empty(t, tbl.loads)
+	require.Len(t, tbl.reloads, 1)
+	if tbl.reloads[0][0] == 1 {
+		require.Equal(t, []int{1, 2}, tbl.oldValues[0])
+	} else {
+		require.Equal(t, []int{2, 1}, tbl.oldValues[0])
+	}
+}
+
+func TestBatchLoader_ContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int64
+	loader := NewBatchLoader[int, int](BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		calls.Add(1)
+		// the batch is not canceled with the caller's context.
+		require.NoError(t, ctx.Err())
+		return map[int]int{1: 1}, nil
+	}), BatchOptions{
+		MaxWait: 50 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := loader.Load(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.Eventually(t, func() bool {
+		return calls.Load() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestBatchLoader_FullBatchContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int64
+	release := make(chan struct{})
+	defer close(release)
+	loader := NewBatchLoader[int, int](BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		calls.Add(1)
+		<-release
+		return map[int]int{1: 1}, nil
+	}), BatchOptions{
+		MaxBatchSize: 1,
+		MaxWait:      time.Hour,
+	})
+
+	// the caller fills the batch, but doesn't wait for the load.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := loader.Load(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.Eventually(t, func() bool {
+		return calls.Load() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestBatchLoader_Panic(t *testing.T) {
+	t.Parallel()
+
+	loader := NewBatchLoader[int, int](BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		panic("boom")
+	}), BatchOptions{})
+	c := Must[int, int](&Options[int, int]{})
+
+	require.Panics(t, func() {
+		_, _ = c.Get(context.Background(), 1, loader)
+	})
+}
@@ -54,6 +54,33 @@ func (co ComputeOp) String() string {
 	return "<unknown otter.ComputeOp>"
 }
 
+// Presence is the state of a key in the cache reported by Cache.Lookup.
+type Presence int
+
+const (
+	// NotCached means that the cache contains neither a value nor a known absence for the key.
+	NotCached Presence = iota
+	// Present means that the cache contains a value for the key.
+	Present
+	// KnownAbsent means that the loader recently reported that the key was not found in the data source,
+	// and the result was cached (see Options.NegativeTTL).
+	KnownAbsent
+)
+
+var presenceStrings = []string{
+	"NotCached",
+	"Present",
+	"KnownAbsent",
+}
+
+// String implements [fmt.Stringer] interface.
+func (p Presence) String() string {
+	if p >= 0 && int(p) < len(presenceStrings) {
+		return presenceStrings[p]
+	}
+	return "<unknown otter.Presence>"
+}
+
 // Cache is an in-memory cache implementation that supports full concurrency of retrievals and multiple ways to bound the cache.
 type Cache[K comparable, V any] struct {
 	cache *cache[K, V]
@@ -102,6 +129,12 @@ func (c *Cache[K, V]) GetIfPresent(key K) (V, bool) {
 	return c.cache.GetIfPresent(key)
 }
 
+// Lookup returns the value associated with the key in this cache, like GetIfPresent, and additionally
+// reports whether the key is known to be absent from the data source (see Options.NegativeTTL).
+func (c *Cache[K, V]) Lookup(key K) (V, Presence) {
+	return c.cache.Lookup(key)
+}
+
 // GetEntry returns the cache entry associated with the key in this cache.
 func (c *Cache[K, V]) GetEntry(key K) (Entry[K, V], bool) {
 	return c.cache.GetEntry(key)
@@ -230,6 +263,8 @@ func (c *Cache[K, V]) SetRefreshableAfter(key K, refreshableAfter time.Duration)
 //
 // Get can return an [ErrNotFound] error if the [Loader] returns it.
 // This means that the entry was not found in the data source.
+// If Options.NegativeTTL is specified, Get returns [ErrKnownAbsent] without calling the [Loader]
+// while the absence of the key is cached.
 //
 // If another call to Get is currently loading the value for key,
 // simply waits for that goroutine to finish and returns its loaded value. Note that
@@ -438,6 +473,15 @@ func (c *Cache[K, V]) Stats() stats.Stats {
 	return c.cache.Stats()
 }
 
+// Diagnostics returns a snapshot of the internal state of the cache, such as the sizes of the eviction
+// policy's spaces, the depth of the read and write buffers and the occupancy of the timer wheel.
+//
+// WARNING: The snapshot is taken within the eviction policy's exclusive lock and counting the entries of
+// the timer wheel takes O(n) time, so Diagnostics should be used only for debugging.
+func (c *Cache[K, V]) Diagnostics() Diagnostics {
+	return c.cache.Diagnostics()
+}
+
 // Hottest returns an iterator for ordered traversal of the cache entries. The order of
 // iteration is from the entries most likely to be retained (hottest) to the entries least
 // likely to be retained (coldest). This order is determined by the eviction policy's best guess
@@ -20,8 +20,11 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"log/slog"
+	"maps"
 	"math"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -86,7 +89,16 @@ type cache[K comparable, V any] struct {
 	expirationPolicy   *expiration.Variable[K, V]
 	stats              stats.Recorder
 	statsSnapshoter    stats.Snapshoter
+	deletionRecorder   stats.DeletionRecorder
+	refreshRecorder    stats.RefreshRecorder
+	groupRecorder      stats.GroupRecorder
+	statsClassifier    func(key K) string
 	logger             Logger
+	debugLogger        DebugLogger
+	tracer             Tracer
+	retrier            *retrier
+	loadTimeout        time.Duration
+	refreshTimeout     time.Duration
 	clock              timeSource
 	statsClock         *realSource
 	readBuffer         *lossy.Striped[K, V]
@@ -101,6 +113,10 @@ type cache[K comparable, V any] struct {
 	expiryCalculator   ExpiryCalculator[K, V]
 	refreshCalculator  RefreshCalculator[K, V]
 	taskPool           sync.Pool
+	wal                atomic.Pointer[WAL[K, V]]
+	graceCalculator    GraceCalculator[K, V]
+	negative           *cache[K, struct{}]
+	stale              *cache[K, any]
 	hasDefaultExecutor bool
 	withTime           bool
 	withExpiration     bool
@@ -139,13 +155,34 @@ func newCache[K comparable, V any](o *Options[K, V]) *cache[K, V] {
 	} else {
 		statsSnapshoter = &stats.NoopRecorder{}
 	}
+	deletionRecorder, _ := statsRecorder.(stats.DeletionRecorder)
+	refreshRecorder, _ := statsRecorder.(stats.RefreshRecorder)
+	var (
+		groupRecorder   stats.GroupRecorder
+		statsClassifier func(key K) string
+	)
+	if gr, ok := statsRecorder.(stats.GroupRecorder); ok && o.StatsClassifier != nil {
+		groupRecorder = gr
+		statsClassifier = o.StatsClassifier
+	}
+
+	logger := o.getLogger()
+	debugLogger, _ := logger.(DebugLogger)
 
 	c := &cache[K, V]{
 		nodeManager:        nodeManager,
 		hashmap:            hashmap.NewWithSize[K, V, node.Node[K, V]](nodeManager, o.getInitialCapacity()),
 		stats:              statsRecorder,
 		statsSnapshoter:    statsSnapshoter,
-		logger:             o.getLogger(),
+		deletionRecorder:   deletionRecorder,
+		refreshRecorder:    refreshRecorder,
+		groupRecorder:      groupRecorder,
+		statsClassifier:    statsClassifier,
+		logger:             logger,
+		debugLogger:        debugLogger,
+		tracer:             o.getTracer(),
+		loadTimeout:        o.LoadTimeout,
+		refreshTimeout:     o.RefreshTimeout,
 		singleflight:       &group[K, V]{},
 		executor:           o.getExecutor(),
 		hasDefaultExecutor: o.Executor == nil,
@@ -156,6 +193,7 @@ func newCache[K comparable, V any](o *Options[K, V]) *cache[K, V] {
 		statsClock:         &realSource{},
 		expiryCalculator:   o.ExpiryCalculator,
 		refreshCalculator:  o.RefreshCalculator,
+		graceCalculator:    o.GraceCalculator,
 		isWeighted:         withWeight,
 		withStats:          withStats,
 	}
@@ -164,6 +202,17 @@ func newCache[K comparable, V any](o *Options[K, V]) *cache[K, V] {
 		c.statsClock.Init()
 	}
 
+	if c.debugLogger != nil {
+		c.hashmap.SetOnResize(c.logResize)
+	}
+
+	if o.NegativeTTL > 0 {
+		c.negative = newCache(o.getNegativeOptions())
+	}
+	if o.GraceCalculator != nil {
+		c.stale = newCache(o.getStaleOptions())
+	}
+
 	c.withEviction = withEviction
 	if c.withEviction {
 		c.evictionPolicy = newPolicy[K, V](withWeight)
@@ -186,7 +235,8 @@ func newCache[K comparable, V any](o *Options[K, V]) *cache[K, V] {
 		c.readBuffer = lossy.NewStriped(maxStripedBufferSize, nodeManager)
 		c.writeBuffer = queue.NewMPSC[task[K, V]](minWriteBufferSize, maxWriteBufferSize)
 	}
-	if c.withTime {
+	c.retrier = newRetrier(o.RetryPolicy, c.clock, debugLogger)
+	if c.withTime || c.retrier != nil {
 		c.clock.Init()
 	}
 	if c.withExpiration {
@@ -261,14 +311,14 @@ func (c *cache[K, V]) GetIfPresent(key K) (V, bool) {
 func (c *cache[K, V]) getNode(key K, nowNano int64) node.Node[K, V] {
 	n := c.hashmap.Get(key)
 	if n == nil {
-		c.stats.RecordMisses(1)
+		c.recordMiss(key)
 		if c.drainStatus.Load() == required {
 			c.scheduleDrainBuffers()
 		}
 		return nil
 	}
 	if n.HasExpired(nowNano) {
-		c.stats.RecordMisses(1)
+		c.recordMiss(key)
 		c.scheduleDrainBuffers()
 		return nil
 	}
@@ -293,14 +343,23 @@ func (c *cache[K, V]) getNodeQuietly(key K, nowNano int64) node.Node[K, V] {
 
 func (c *cache[K, V]) afterRead(got node.Node[K, V], nowNano int64, recordHit, calcExpiresAt bool) {
 	if recordHit {
-		c.stats.RecordHits(1)
+		c.recordHit(got.Key())
 	}
 
 	if calcExpiresAt {
 		c.calcExpiresAtAfterRead(got, nowNano)
 	}
 
-	delayable := c.skipReadBuffer() || c.readBuffer.Add(got) != lossy.Full
+	delayable := true
+	if !c.skipReadBuffer() {
+		status := c.readBuffer.Add(got)
+		delayable = status != lossy.Full
+		if status == lossy.Full && c.debugEnabled() {
+			c.debugLogger.Debug(context.Background(), "Dropped a read buffer event",
+				slog.Any("key", got.Key()),
+			)
+		}
+	}
 	if c.shouldDrainBuffers(delayable) {
 		c.scheduleDrainBuffers()
 	}
@@ -384,6 +443,7 @@ func (c *cache[K, V]) SetExpiresAfter(key K, expiresAfter time.Duration) {
 
 	c.setExpiresAfterRead(n, nowNano, expiresAfter)
 	c.afterRead(n, nowNano, false, false)
+	c.logUpdate(n, nowNano)
 }
 
 // SetRefreshableAfter specifies that each entry should be eligible for reloading once a fixed duration has elapsed.
@@ -403,6 +463,7 @@ func (c *cache[K, V]) SetRefreshableAfter(key K, refreshableAfter time.Duration)
 	currentDuration := entry.RefreshableAfter()
 	if refreshableAfter > 0 && currentDuration != refreshableAfter {
 		n.SetRefreshableAt(nowNano + int64(refreshableAfter))
+		c.logUpdate(n, nowNano)
 	}
 }
 
@@ -458,6 +519,8 @@ func (c *cache[K, V]) atomicSet(key K, value V, old node.Node[K, V], cl *call[K,
 	if cl == nil {
 		c.singleflight.delete(key)
 	}
+	c.forgetAbsent(key)
+	c.forgetStale(key)
 	n := c.newNode(key, value, old)
 	c.calcExpiresAtAfterWrite(n, old, nowNano)
 	c.calcRefreshableAt(n, old, cl, nowNano)
@@ -466,6 +529,7 @@ func (c *cache[K, V]) atomicSet(key K, value V, old node.Node[K, V], cl *call[K,
 		cause := getCause(old, nowNano, CauseReplacement)
 		c.notifyAtomicDeletion(old.Key(), old.Value(), cause)
 	}
+	c.logAtomicSet(n, nowNano)
 	return n
 }
 
@@ -474,10 +538,12 @@ func (c *cache[K, V]) atomicDelete(key K, old node.Node[K, V], cl *call[K, V], n
 	if cl == nil {
 		c.singleflight.delete(key)
 	}
+	c.forgetStale(key)
 	if old != nil {
 		cause := getCause(old, nowNano, CauseInvalidation)
 		c.makeRetired(old)
 		c.notifyAtomicDeletion(old.Key(), old.Value(), cause)
+		c.logAtomicDelete(key)
 	}
 	return nil
 }
@@ -645,9 +711,9 @@ func (c *cache[K, V]) doCompute(
 	}
 	if recordStats {
 		if old != nil && !old.HasExpired(nowNano) {
-			c.stats.RecordHits(1)
+			c.recordHit(key)
 		} else {
-			c.stats.RecordMisses(1)
+			c.recordMiss(key)
 		}
 	}
 	switch op {
@@ -671,6 +737,7 @@ func (c *cache[K, V]) doCompute(
 func (c *cache[K, V]) afterWrite(n, old node.Node[K, V], nowNano int64) {
 	if !c.withMaintenance {
 		if old != nil {
+			c.recordDeletion(old, CauseReplacement)
 			c.notifyDeletion(old.Key(), old.Value(), CauseReplacement)
 		}
 		return
@@ -716,15 +783,26 @@ func (c *cache[K, V]) refreshKey(
 		} else {
 			refresher = loader.Load
 		}
+		refresher = withTimeout(c.refreshTimeout, withRetries(c.retrier, refresher))
 
 		cl, shouldLoad := c.singleflight.startCall(rk.key, true)
-		if shouldLoad {
-			//nolint:errcheck // there is no need to check error
-			_ = c.wrapLoad(func() error {
-				loadCtx := context.WithoutCancel(ctx)
-				return c.singleflight.doCall(loadCtx, cl, refresher, c.afterDeleteCall)
-			})
+		info := LoadInfo{
+			Operation: OperationRefresh,
+			KeyCount:  1,
+			Coalesced: !shouldLoad,
 		}
+		//nolint:errcheck // there is no need to check error
+		_ = c.traceLoad(ctx, info, func(ctx context.Context) error {
+			if shouldLoad {
+				return c.wrapRefresh(func() error {
+					loadCtx := context.WithoutCancel(ctx)
+					return c.singleflight.doCall(loadCtx, cl, refresher, c.afterDeleteCall)
+				}, rk.key)
+			}
+			c.recordCoalescedLoads(1)
+			cl.wait()
+			return cl.err
+		})
 		cl.wait()
 
 		if cl.err != nil && !cl.isNotFound {
@@ -748,6 +826,12 @@ func (c *cache[K, V]) refreshKey(
 //
 // Get can return an ErrNotFound error if the Loader returns it.
 // This means that the entry was not found in the data source.
+// If Options.NegativeTTL is specified, Get returns ErrKnownAbsent without calling the Loader
+// while the absence of the key is cached.
+//
+// If Options.GraceCalculator is specified and the Loader fails to load the new value of an expired entry,
+// Get returns the expired value and an error that wraps both ErrStale and the error of the Loader
+// while the entry is within its grace period.
 //
 // If another call to Get is currently loading the value for key,
 // simply waits for that goroutine to finish and returns its loaded value. Note that
@@ -769,6 +853,7 @@ func (c *cache[K, V]) Get(ctx context.Context, key K, loader Loader[K, V]) (V, e
 	n := c.getNode(key, nowNano)
 	if n != nil {
 		if !n.IsFresh(nowNano) {
+			c.recordStaleServes(1)
 			c.refreshKey(ctx, refreshableKey[K, V]{
 				key: n.Key(),
 				old: n,
@@ -776,16 +861,35 @@ func (c *cache[K, V]) Get(ctx context.Context, key K, loader Loader[K, V]) (V, e
 		}
 		return n.Value(), nil
 	}
+	if c.isKnownAbsent(key) {
+		return zeroValue[V](), ErrKnownAbsent
+	}
 
 	cl, shouldLoad := c.singleflight.startCall(key, false)
-	if shouldLoad {
-		//nolint:errcheck // there is no need to check error
-		_ = c.wrapLoad(func() error {
-			return c.singleflight.doCall(ctx, cl, loader.Load, c.afterDeleteCall)
-		})
+	info := LoadInfo{
+		Operation: OperationLoad,
+		KeyCount:  1,
+		Coalesced: !shouldLoad,
 	}
+	//nolint:errcheck // there is no need to check error
+	_ = c.traceLoad(ctx, info, func(ctx context.Context) error {
+		if shouldLoad {
+			return c.wrapLoad(func() error {
+				load := withTimeout(c.loadTimeout, withRetries(c.retrier, loader.Load))
+				return c.singleflight.doCall(ctx, cl, load, c.afterDeleteCall)
+			}, key)
+		}
+		c.recordCoalescedLoads(1)
+		cl.wait()
+		return cl.err
+	})
 	cl.wait()
 
+	if cl.err != nil && !cl.isNotFound {
+		if v, ok := c.getStale(key); ok {
+			return v, errors.Join(ErrStale, cl.err)
+		}
+	}
 	return cl.value, cl.err
 }
 
@@ -829,6 +933,8 @@ func (c *cache[K, V]) afterDeleteCall(cl *call[K, V]) {
 		isCorrectCall := cl.isFake || c.singleflight.deleteCall(cl)
 		old = oldNode
 		if isCorrectCall && cl.isNotFound {
+			// the absence is recorded under the lock of the key, so a concurrent write can't be overridden.
+			c.markAbsent(cl.key)
 			deleted = oldNode != nil
 			return c.atomicDelete(cl.key, oldNode, cl, nowNano)
 		}
@@ -908,10 +1014,19 @@ func (c *cache[K, V]) bulkRefreshKeys(
 			i++
 		}
 
-		loadCtx := context.WithoutCancel(ctx)
+		c.recordCoalescedLoads(len(foundCalls))
+
 		if len(toLoadCalls) > 0 {
-			loadErr := c.wrapLoad(func() error {
-				return c.singleflight.doBulkCall(loadCtx, toLoadCalls, bulkLoader.BulkLoad, c.afterDeleteCall)
+			info := LoadInfo{
+				Operation: OperationBulkRefresh,
+				KeyCount:  len(toLoadCalls),
+			}
+			loadErr := c.traceLoad(ctx, info, func(ctx context.Context) error {
+				return c.wrapRefresh(func() error {
+					loadCtx := context.WithoutCancel(ctx)
+					bulkLoad := withTimeout(c.refreshTimeout, withRetries(c.retrier, bulkLoader.BulkLoad))
+					return c.singleflight.doBulkCall(loadCtx, toLoadCalls, bulkLoad, c.afterDeleteCall)
+				}, c.groupedKeys(toLoadCalls)...)
 			})
 			if loadErr != nil {
 				c.logger.Error(ctx, "BulkLoad returned an error", loadErr)
@@ -935,11 +1050,21 @@ func (c *cache[K, V]) bulkRefreshKeys(
 					oldValues = append(oldValues, cl.value)
 					cl.value = zeroValue[V]()
 				}
-				return bulkLoader.BulkReload(ctx, keys, oldValues)
+				bulkReload := withRetries(c.retrier, func(ctx context.Context, keys []K) (map[K]V, error) {
+					return bulkLoader.BulkReload(ctx, keys, oldValues)
+				})
+				return withTimeout(c.refreshTimeout, bulkReload)(ctx, keys)
 			}
 
-			reloadErr := c.wrapLoad(func() error {
-				return c.singleflight.doBulkCall(loadCtx, toReloadCalls, reload, c.afterDeleteCall)
+			info := LoadInfo{
+				Operation: OperationBulkRefresh,
+				KeyCount:  len(toReloadCalls),
+			}
+			reloadErr := c.traceLoad(ctx, info, func(ctx context.Context) error {
+				return c.wrapRefresh(func() error {
+					loadCtx := context.WithoutCancel(ctx)
+					return c.singleflight.doBulkCall(loadCtx, toReloadCalls, reload, c.afterDeleteCall)
+				}, c.groupedKeys(toReloadCalls)...)
 			})
 			if reloadErr != nil {
 				c.logger.Error(ctx, "BulkReload returned an error", reloadErr)
@@ -955,6 +1080,17 @@ func (c *cache[K, V]) bulkRefreshKeys(
 				}
 			}
 		}
+		if c.tracer != nil && len(foundCalls) > 0 {
+			info := LoadInfo{
+				Operation: OperationBulkRefresh,
+				KeyCount:  len(foundCalls),
+				Coalesced: true,
+			}
+			//nolint:errcheck // there is no need to check error
+			_ = c.traceLoad(ctx, info, func(ctx context.Context) error {
+				return waitCalls(foundCalls)
+			})
+		}
 		for _, cl := range foundCalls {
 			cl.wait()
 			if isManual {
@@ -980,6 +1116,9 @@ func (c *cache[K, V]) bulkRefreshKeys(
 // simply waits for that goroutine to finish and returns its loaded value. Note that
 // multiple goroutines can concurrently load values for distinct keys.
 //
+// If Options.GraceCalculator is specified, the expired values of the keys that failed to load and
+// are within their grace period are added to the result, and the returned error also wraps ErrStale.
+//
 // No observable state associated with this cache is modified until loading completes.
 //
 // WARNING: BulkLoader.BulkLoad must not attempt to update any mappings of this cache directly.
@@ -1022,6 +1161,9 @@ func (c *cache[K, V]) BulkGet(ctx context.Context, keys []K, bulkLoader BulkLoad
 			result[key] = n.Value()
 			continue
 		}
+		if c.isKnownAbsent(key) {
+			continue
+		}
 
 		if misses == nil {
 			misses = make(map[K]*call[K, V], len(keys)-len(result))
@@ -1029,6 +1171,7 @@ func (c *cache[K, V]) BulkGet(ctx context.Context, keys []K, bulkLoader BulkLoad
 		misses[key] = nil
 	}
 
+	c.recordStaleServes(len(toRefresh))
 	c.bulkRefreshKeys(ctx, toRefresh, bulkLoader, false)
 	if len(misses) == 0 {
 		return result, nil
@@ -1047,19 +1190,48 @@ func (c *cache[K, V]) BulkGet(ctx context.Context, keys []K, bulkLoader BulkLoad
 		misses[key] = cl
 		i++
 	}
+	c.recordCoalescedLoads(len(misses) - len(toLoadCalls))
 
 	var loadErr error
 	if len(toLoadCalls) > 0 {
-		loadErr = c.wrapLoad(func() error {
-			return c.singleflight.doBulkCall(ctx, toLoadCalls, bulkLoader.BulkLoad, c.afterDeleteCall)
+		info := LoadInfo{
+			Operation: OperationBulkLoad,
+			KeyCount:  len(toLoadCalls),
+		}
+		loadErr = c.traceLoad(ctx, info, func(ctx context.Context) error {
+			return c.wrapLoad(func() error {
+				bulkLoad := withTimeout(c.loadTimeout, withRetries(c.retrier, bulkLoader.BulkLoad))
+				return c.singleflight.doBulkCall(ctx, toLoadCalls, bulkLoad, c.afterDeleteCall)
+			}, c.groupedKeys(toLoadCalls)...)
 		})
 	}
 	if loadErr != nil {
-		return result, loadErr
+		return result, c.addStale(result, maps.Values(toLoadCalls), loadErr)
+	}
+
+	if coalesced := len(misses) - len(toLoadCalls); c.tracer != nil && coalesced > 0 {
+		coalescedCalls := make([]*call[K, V], 0, coalesced)
+		for key, cl := range misses {
+			if _, ok := toLoadCalls[key]; !ok {
+				coalescedCalls = append(coalescedCalls, cl)
+			}
+		}
+		info := LoadInfo{
+			Operation: OperationBulkLoad,
+			KeyCount:  coalesced,
+			Coalesced: true,
+		}
+		//nolint:errcheck // there is no need to check error
+		_ = c.traceLoad(ctx, info, func(ctx context.Context) error {
+			return waitCalls(coalescedCalls)
+		})
 	}
 
 	//nolint:prealloc // it's ok
-	var errsFromCalls []error
+	var (
+		errsFromCalls []error
+		failedCalls   []*call[K, V]
+	)
 	i = 0
 	for key, cl := range misses {
 		cl.wait()
@@ -1076,26 +1248,184 @@ func (c *cache[K, V]) BulkGet(ctx context.Context, keys []K, bulkLoader BulkLoad
 			errsFromCalls = make([]error, 0, len(misses)-i+1)
 		}
 		errsFromCalls = append(errsFromCalls, cl.err)
+		failedCalls = append(failedCalls, cl)
 	}
 
 	var err error
 	if len(errsFromCalls) > 0 {
-		err = errors.Join(errsFromCalls...)
+		err = c.addStale(result, slices.Values(failedCalls), errors.Join(errsFromCalls...))
 	}
 
 	return result, err
 }
 
-func (c *cache[K, V]) wrapLoad(fn func() error) error {
+// Lookup returns the value associated with the key in this cache, like GetIfPresent, and additionally
+// reports whether the key is known to be absent from the data source (see Options.NegativeTTL).
+func (c *cache[K, V]) Lookup(key K) (V, Presence) {
+	if v, ok := c.GetIfPresent(key); ok {
+		return v, Present
+	}
+	if c.isKnownAbsent(key) {
+		return zeroValue[V](), KnownAbsent
+	}
+	return zeroValue[V](), NotCached
+}
+
+// isKnownAbsent reports whether the loader recently reported that the key was not found in the data source.
+func (c *cache[K, V]) isKnownAbsent(key K) bool {
+	return c.negative != nil && c.negative.has(key)
+}
+
+func (c *cache[K, V]) markAbsent(key K) {
+	if c.negative != nil {
+		c.negative.Set(key, struct{}{})
+	}
+}
+
+func (c *cache[K, V]) forgetAbsent(key K) {
+	if c.negative != nil {
+		c.negative.Invalidate(key)
+	}
+}
+
+// staleValue is an expired value that may be served until the deadline.
+type staleValue[V any] struct {
+	value    V
+	deadline int64
+}
+
+// retainStale keeps the expired entry for its grace period.
+func (c *cache[K, V]) retainStale(n node.Node[K, V], nowNano int64) {
+	grace := c.graceCalculator.GraceAfterExpiry(c.nodeToEntry(n, nowNano))
+	deadline := n.ExpiresAt() + int64(grace)
+	if grace <= 0 || deadline <= nowNano {
+		return
+	}
+	c.stale.Set(n.Key(), staleValue[V]{
+		value:    n.Value(),
+		deadline: deadline,
+	})
+}
+
+// getStale returns the expired value of the key if the entry is within its grace period.
+func (c *cache[K, V]) getStale(key K) (V, bool) {
+	if c.stale == nil {
+		return zeroValue[V](), false
+	}
+
+	nowNano := c.clock.NowNano()
+	if n := c.hashmap.Get(key); n != nil && n.IsAlive() && n.HasExpired(nowNano) {
+		// the expired entry is not evicted yet.
+		grace := c.graceCalculator.GraceAfterExpiry(c.nodeToEntry(n, nowNano))
+		if grace > 0 && n.ExpiresAt()+int64(grace) > nowNano {
+			return n.Value(), true
+		}
+		return zeroValue[V](), false
+	}
+	if v, ok := c.stale.GetIfPresent(key); ok {
+		//nolint:errcheck // the stale cache contains only staleValue[V]
+		return v.(staleValue[V]).value, true
+	}
+	return zeroValue[V](), false
+}
+
+// addStale adds the expired values of the failed calls that are within their grace period to the result
+// and wraps ErrStale into err if any value is added.
+func (c *cache[K, V]) addStale(result map[K]V, calls iter.Seq[*call[K, V]], err error) error {
+	if c.stale == nil {
+		return err
+	}
+
+	var served bool
+	for cl := range calls {
+		if cl.isFake || cl.isNotFound || cl.err == nil {
+			continue
+		}
+		if v, ok := c.getStale(cl.key); ok {
+			result[cl.key] = v
+			served = true
+		}
+	}
+	if served {
+		return errors.Join(ErrStale, err)
+	}
+	return err
+}
+
+func (c *cache[K, V]) forgetStale(key K) {
+	if c.stale != nil {
+		c.stale.Invalidate(key)
+	}
+}
+
+// traceLoad calls fn with the context returned by the tracer and notifies the tracer about the end of the operation.
+func (c *cache[K, V]) traceLoad(ctx context.Context, info LoadInfo, fn func(ctx context.Context) error) error {
+	if c.tracer == nil {
+		return fn(ctx)
+	}
+
+	ctx = c.tracer.StartLoad(ctx, info)
+	defer func() {
+		// the panics of the loader are rethrown by wrapCall.
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				err = newPanicError(r)
+			}
+			c.tracer.EndLoad(ctx, info, err)
+			panic(r)
+		}
+	}()
+	err := fn(ctx)
+	c.tracer.EndLoad(ctx, info, err)
+	return err
+}
+
+// waitCalls waits for the calls to complete and returns their joined errors.
+func waitCalls[K comparable, V any](calls []*call[K, V]) error {
+	var errs []error
+	for _, cl := range calls {
+		cl.wait()
+		if cl.err != nil {
+			errs = append(errs, cl.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// wrapLoad calls fn and records the statistics of the load of keys.
+//
+// The keys are used only to record the statistics of their groups.
+func (c *cache[K, V]) wrapLoad(fn func() error, keys ...K) error {
+	return c.wrapCall(fn, false, keys)
+}
+
+// wrapRefresh is like wrapLoad, but also records the statistics of a refresh if the stats recorder supports it.
+func (c *cache[K, V]) wrapRefresh(fn func() error, keys ...K) error {
+	return c.wrapCall(fn, true, keys)
+}
+
+func (c *cache[K, V]) wrapCall(fn func() error, isRefresh bool, keys []K) error {
 	startTime := c.statsClock.NowNano()
 
 	err := fn()
 
 	loadTime := time.Duration(c.statsClock.NowNano() - startTime)
-	if err == nil || errors.Is(err, ErrNotFound) {
-		c.stats.RecordLoadSuccess(loadTime)
-	} else {
-		c.stats.RecordLoadFailure(loadTime)
+	isSuccess := err == nil || errors.Is(err, ErrNotFound)
+	recordLoad(c.stats, c.refreshRecorder, isRefresh, isSuccess, loadTime)
+	if c.groupRecorder != nil {
+		// every group is recorded once per call.
+		groups := make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			group := c.statsClassifier(key)
+			if _, ok := groups[group]; ok {
+				continue
+			}
+			groups[group] = struct{}{}
+			r := c.groupRecorder.Group(group)
+			rr, _ := r.(stats.RefreshRecorder)
+			recordLoad(r, rr, isRefresh, isSuccess, loadTime)
+		}
 	}
 
 	var pe *panicError
@@ -1185,6 +1515,7 @@ func (c *cache[K, V]) BulkRefresh(ctx context.Context, keys []K, bulkLoader Bulk
 // Returns previous value if any. The invalidated result reports whether the key was
 // present.
 func (c *cache[K, V]) Invalidate(key K) (value V, invalidated bool) {
+	c.forgetAbsent(key)
 	var d node.Node[K, V]
 	nowNano := c.clock.NowNano()
 	c.hashmap.Compute(key, func(n node.Node[K, V]) node.Node[K, V] {
@@ -1210,6 +1541,7 @@ func (c *cache[K, V]) deleteNodeFromMap(n node.Node[K, V], nowNano int64, cause
 			cause := getCause(deleted, nowNano, cause)
 			c.makeRetired(deleted)
 			c.notifyAtomicDeletion(deleted.Key(), deleted.Value(), cause)
+			c.logAtomicDelete(deleted.Key())
 			return nil
 		}
 		return current
@@ -1227,6 +1559,7 @@ func (c *cache[K, V]) afterDelete(deleted node.Node[K, V], nowNano int64, alread
 	}
 
 	if !c.withMaintenance {
+		c.recordDeletion(deleted, CauseInvalidation)
 		c.notifyDeletion(deleted.Key(), deleted.Value(), CauseInvalidation)
 		return
 	}
@@ -1241,6 +1574,89 @@ func (c *cache[K, V]) afterDelete(deleted node.Node[K, V], nowNano int64, alread
 	}
 }
 
+// groupedKeys returns the keys of calls if the statistics are recorded per group, and nil otherwise.
+func (c *cache[K, V]) groupedKeys(calls map[K]*call[K, V]) []K {
+	if c.groupRecorder == nil {
+		return nil
+	}
+	keys := make([]K, 0, len(calls))
+	for key := range calls {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// groupStats returns the stats recorder of the key's group, or nil if the statistics aren't recorded per group.
+func (c *cache[K, V]) groupStats(key K) stats.Recorder {
+	if c.groupRecorder == nil {
+		return nil
+	}
+	return c.groupRecorder.Group(c.statsClassifier(key))
+}
+
+func (c *cache[K, V]) recordHit(key K) {
+	c.stats.RecordHits(1)
+	if r := c.groupStats(key); r != nil {
+		r.RecordHits(1)
+	}
+}
+
+func (c *cache[K, V]) recordMiss(key K) {
+	c.stats.RecordMisses(1)
+	if r := c.groupStats(key); r != nil {
+		r.RecordMisses(1)
+	}
+}
+
+func (c *cache[K, V]) recordStaleServes(count int) {
+	if c.refreshRecorder != nil && count > 0 {
+		c.refreshRecorder.RecordStaleServes(count)
+	}
+}
+
+func (c *cache[K, V]) recordCoalescedLoads(count int) {
+	if c.refreshRecorder != nil && count > 0 {
+		c.refreshRecorder.RecordCoalescedLoads(count)
+	}
+}
+
+// recordDeletion records the deletion of the node to the stats recorder.
+func (c *cache[K, V]) recordDeletion(n node.Node[K, V], cause DeletionCause) {
+	recordDeletion(c.stats, c.deletionRecorder, cause, n.Weight())
+	if r := c.groupStats(n.Key()); r != nil {
+		dr, _ := r.(stats.DeletionRecorder)
+		recordDeletion(r, dr, cause, n.Weight())
+	}
+}
+
+// recordDeletion records the deletion to dr if it's not nil, and the eviction to r otherwise.
+func recordDeletion(r stats.Recorder, dr stats.DeletionRecorder, cause DeletionCause, weight uint32) {
+	if dr != nil {
+		dr.RecordDeletion(stats.DeletionCause(cause), weight)
+		return
+	}
+	if cause.IsEviction() {
+		r.RecordEviction(weight)
+	}
+}
+
+// recordLoad records the load to r and, if it's a refresh, also to rr if it's not nil.
+func recordLoad(r stats.Recorder, rr stats.RefreshRecorder, isRefresh, isSuccess bool, loadTime time.Duration) {
+	if isSuccess {
+		r.RecordLoadSuccess(loadTime)
+	} else {
+		r.RecordLoadFailure(loadTime)
+	}
+	if !isRefresh || rr == nil {
+		return
+	}
+	if isSuccess {
+		rr.RecordRefreshSuccess(loadTime)
+	} else {
+		rr.RecordRefreshFailure(loadTime)
+	}
+}
+
 func (c *cache[K, V]) notifyDeletion(key K, value V, cause DeletionCause) {
 	if c.onDeletion == nil {
 		return
@@ -1298,8 +1714,82 @@ func (c *cache[K, V]) evictNode(n node.Node[K, V], nowNanos int64) {
 	c.makeDead(n)
 
 	if deleted {
+		if cause == CauseExpiration && c.stale != nil {
+			c.retainStale(n, nowNanos)
+		}
+		c.logDelete(n.Key())
+		c.recordDeletion(n, cause)
 		c.notifyDeletion(n.Key(), n.Value(), cause)
-		c.stats.RecordEviction(n.Weight())
+		if c.debugEnabled() {
+			c.debugLogger.Debug(context.Background(), "Evicted an entry",
+				slog.Any("key", n.Key()),
+				slog.String("cause", cause.String()),
+				slog.Uint64("weight", uint64(n.Weight())),
+			)
+		}
+	}
+}
+
+// debugEnabled reports whether the debug events should be logged.
+func (c *cache[K, V]) debugEnabled() bool {
+	return c.debugLogger != nil && c.debugLogger.DebugEnabled(context.Background())
+}
+
+func (c *cache[K, V]) logResize(oldCapacity, newCapacity int) {
+	if c.debugEnabled() {
+		c.debugLogger.Debug(context.Background(), "Resized the hash table",
+			slog.Int("old_capacity", oldCapacity),
+			slog.Int("new_capacity", newCapacity),
+		)
+	}
+}
+
+// logSet records the current state of the node to the attached WAL, if any.
+func (c *cache[K, V]) logSet(n node.Node[K, V], nowNano int64) {
+	if w := c.wal.Load(); w != nil && n.IsAlive() {
+		w.recordSet(c.nodeToEntry(n, nowNano))
+	}
+}
+
+// logDelete records the deletion of the key to the attached WAL, if any.
+func (c *cache[K, V]) logDelete(key K) {
+	if w := c.wal.Load(); w != nil {
+		w.recordDelete(key)
+	}
+}
+
+// logUpdate records the changed deadlines of the node to the attached WAL, if any.
+func (c *cache[K, V]) logUpdate(n node.Node[K, V], nowNano int64) {
+	if c.wal.Load() == nil {
+		return
+	}
+	if c.withMaintenance {
+		// the replaced nodes are retired, so a stale node is not logged.
+		c.logSet(n, nowNano)
+		return
+	}
+	c.hashmap.Compute(n.Key(), func(current node.Node[K, V]) node.Node[K, V] {
+		if current != nil && current.AsPointer() == n.AsPointer() {
+			c.logSet(n, nowNano)
+		}
+		return current
+	})
+}
+
+// logAtomicSet records the new node to the attached WAL under the lock of its key
+// if the cache doesn't have maintenance. Otherwise, the node is logged by the maintenance,
+// which orders the changes of a key.
+func (c *cache[K, V]) logAtomicSet(n node.Node[K, V], nowNano int64) {
+	if !c.withMaintenance {
+		c.logSet(n, nowNano)
+	}
+}
+
+// logAtomicDelete records the deletion of the key to the attached WAL under the lock of the key
+// if the cache doesn't have maintenance.
+func (c *cache[K, V]) logAtomicDelete(key K) {
+	if !c.withMaintenance {
+		c.logDelete(key)
 	}
 }
 
@@ -1377,6 +1867,12 @@ func (c *cache[K, V]) Values() iter.Seq[V] {
 // InvalidateAll discards all entries in the cache. The behavior of this operation is undefined for an entry
 // that is being loaded (or reloaded) and is otherwise not present.
 func (c *cache[K, V]) InvalidateAll() {
+	if c.negative != nil {
+		c.negative.InvalidateAll()
+	}
+	if c.stale != nil {
+		c.stale.InvalidateAll()
+	}
 	c.evictionMutex.Lock()
 
 	if c.withMaintenance {
@@ -1397,7 +1893,7 @@ func (c *cache[K, V]) InvalidateAll() {
 		return true
 	})
 	nowNano := c.clock.NowNano()
-	for len(nodes) > 0 && c.writeBuffer.Size() < threshold {
+	for len(nodes) > 0 && (!c.withMaintenance || c.writeBuffer.Size() < threshold) {
 		n := nodes[len(nodes)-1]
 		nodes = nodes[:len(nodes)-1]
 		c.deleteNode(n, nowNano)
@@ -1585,6 +2081,7 @@ func (c *cache[K, V]) runTask(t *task[K, V]) {
 	n := t.node()
 	switch t.writeReason {
 	case addReason:
+		c.logSet(n, c.clock.NowNano())
 		if c.withExpiration && n.IsAlive() {
 			c.expirationPolicy.Add(n)
 		}
@@ -1592,6 +2089,7 @@ func (c *cache[K, V]) runTask(t *task[K, V]) {
 			c.evictionPolicy.add(n, c.evictNode)
 		}
 	case updateReason:
+		c.logSet(n, c.clock.NowNano())
 		old := t.oldNode()
 		if c.withExpiration {
 			c.expirationPolicy.Delete(old)
@@ -1602,14 +2100,20 @@ func (c *cache[K, V]) runTask(t *task[K, V]) {
 		if c.withEviction {
 			c.evictionPolicy.update(n, old, c.evictNode)
 		}
+		c.recordDeletion(old, t.deletionCause)
 		c.notifyDeletion(old.Key(), old.Value(), t.deletionCause)
 	case deleteReason:
+		if c.hashmap.Get(n.Key()) == nil {
+			// otherwise, the key was set again after the deletion, and the new node is logged by its own task.
+			c.logDelete(n.Key())
+		}
 		if c.withExpiration {
 			c.expirationPolicy.Delete(n)
 		}
 		if c.withEviction {
 			c.evictionPolicy.delete(n)
 		}
+		c.recordDeletion(n, t.deletionCause)
 		c.notifyDeletion(n.Key(), n.Value(), t.deletionCause)
 	default:
 		panic(fmt.Sprintf("Invalid task type: %d", t.writeReason))
@@ -1647,7 +2151,15 @@ func (c *cache[K, V]) climb() {
 	if !c.withEviction {
 		return
 	}
+	oldWindowMaximum := c.evictionPolicy.windowMaximum
 	c.evictionPolicy.climb()
+	if windowMaximum := c.evictionPolicy.windowMaximum; windowMaximum != oldWindowMaximum && c.debugEnabled() {
+		c.debugLogger.Debug(context.Background(), "Adjusted the window size",
+			slog.Uint64("old_window_maximum", oldWindowMaximum),
+			slog.Uint64("window_maximum", windowMaximum),
+			slog.Float64("sample_hit_rate", c.evictionPolicy.previousSampleHitRate),
+		)
+	}
 }
 
 func (c *cache[K, V]) getTask(n, old node.Node[K, V], writeReason reason, cause DeletionCause) *task[K, V] {
@@ -1715,6 +2227,12 @@ func (c *cache[K, V]) close() {
 	if c.withExpiration {
 		c.doneClose <- struct{}{}
 	}
+	if c.negative != nil {
+		c.negative.close()
+	}
+	if c.stale != nil {
+		c.stale.close()
+	}
 }
 
 // EstimatedSize returns the approximate number of entries in this cache. The value returned is an estimate; the
@@ -1772,7 +2290,7 @@ func (c *cache[K, V]) WeightedSize() uint64 {
 // iteration should be short and simple. While the iteration is in progress further eviction
 // maintenance will be halted.
 func (c *cache[K, V]) Hottest() iter.Seq[Entry[K, V]] {
-	return c.evictionOrder(true)
+	return c.evictionOrder(true, nil)
 }
 
 // Coldest returns an iterator for ordered traversal of the cache entries. The order of
@@ -1784,10 +2302,13 @@ func (c *cache[K, V]) Hottest() iter.Seq[Entry[K, V]] {
 // iteration should be short and simple. While the iteration is in progress further eviction
 // maintenance will be halted.
 func (c *cache[K, V]) Coldest() iter.Seq[Entry[K, V]] {
-	return c.evictionOrder(false)
+	return c.evictionOrder(false, nil)
 }
 
-func (c *cache[K, V]) evictionOrder(hottest bool) iter.Seq[Entry[K, V]] {
+// evictionOrder returns an iterator over the entries in the eviction order.
+//
+// If ps is not nil, the state of the eviction policy and the states of the yielded entries are saved to it.
+func (c *cache[K, V]) evictionOrder(hottest bool, ps *policyState) iter.Seq[Entry[K, V]] {
 	if !c.withEviction {
 		return c.entries()
 	}
@@ -1830,11 +2351,18 @@ func (c *cache[K, V]) evictionOrder(hottest bool) iter.Seq[Entry[K, V]] {
 		defer c.evictionMutex.Unlock()
 		c.maintenance(nil)
 
+		if ps != nil {
+			ps.windowMaximum = c.evictionPolicy.windowMaximum
+			ps.mainProtectedMaximum = c.evictionPolicy.mainProtectedMaximum
+		}
 		for n := range seq {
 			nowNano := c.clock.NowNano()
 			if !n.IsAlive() || n.HasExpired(nowNano) {
 				continue
 			}
+			if ps != nil {
+				ps.nodes = append(ps.nodes, c.evictionPolicy.nodeState(n))
+			}
 			if !yield(c.nodeToEntry(n, nowNano)) {
 				return
 			}
@@ -1842,6 +2370,39 @@ func (c *cache[K, V]) evictionOrder(hottest bool) iter.Seq[Entry[K, V]] {
 	}
 }
 
+// restorePolicy restores the state of the eviction policy saved by evictionOrder.
+// The keys are ordered from the hottest to the coldest.
+func (c *cache[K, V]) restorePolicy(maximum uint64, ps policyState, keys []K, states []nodeState) {
+	if !c.withEviction {
+		return
+	}
+
+	c.evictionMutex.Lock()
+	defer c.evictionMutex.Unlock()
+
+	// apply the pending additions, so that the loaded nodes are in the queues.
+	for {
+		t := c.writeBuffer.TryPop()
+		if t == nil {
+			break
+		}
+		c.runTask(t)
+	}
+
+	nodes := make([]node.Node[K, V], 0, len(keys))
+	nodeStates := make([]nodeState, 0, len(keys))
+	for i, key := range keys {
+		n := c.hashmap.Get(key)
+		if n == nil || !n.IsAlive() {
+			continue
+		}
+		nodes = append(nodes, n)
+		nodeStates = append(nodeStates, states[i])
+	}
+	c.evictionPolicy.restore(maximum, ps, nodes, nodeStates)
+	c.maintenance(nil)
+}
+
 func (c *cache[K, V]) makeRetired(n node.Node[K, V]) {
 	if n != nil && c.withMaintenance && n.IsAlive() {
 		n.Retire()
@@ -16,11 +16,14 @@ package otter
 
 import (
 	"container/heap"
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
 	"reflect"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -488,6 +491,19 @@ func TestCache_InvalidateAll(t *testing.T) {
 	}
 }
 
+func TestCache_InvalidateAllUnbounded(t *testing.T) {
+	t.Parallel()
+
+	// the cache has no write buffer, since it neither evicts nor expires entries.
+	c := Must(&Options[int, int]{})
+	for i := 0; i < 10; i++ {
+		c.Set(i, i)
+	}
+
+	c.InvalidateAll()
+	require.Equal(t, 0, c.EstimatedSize())
+}
+
 func TestCache_Set(t *testing.T) {
 	t.Parallel()
 
@@ -1321,6 +1337,125 @@ func TestCache_Stats(t *testing.T) {
 	require.Equal(t, stats.Stats{}, cache.Stats())
 }
 
+func TestCache_WindowedStats(t *testing.T) {
+	t.Parallel()
+
+	mc := newManualClock()
+	counter := stats.NewWindowedCounter(mc, time.Minute)
+	cache := Must(&Options[int, int]{
+		StatsRecorder: counter,
+		Clock:         mc,
+	})
+
+	for i := 0; i < 10; i++ {
+		cache.Set(i, i)
+		cache.GetIfPresent(i)
+	}
+	mc.advance(2 * time.Minute)
+	cache.GetIfPresent(100)
+
+	require.Equal(t, stats.Stats{Misses: 1}, counter.Window(time.Minute))
+	require.Equal(t, 1.0/11, cache.Stats().MissRatio())
+}
+
+func TestCache_GroupedStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	counter := stats.NewGroupedCounter()
+	cache := Must(&Options[string, int]{
+		MaximumSize:   10,
+		StatsRecorder: counter,
+		StatsClassifier: func(key string) string {
+			tenant, _, _ := strings.Cut(key, ":")
+			return tenant
+		},
+	})
+
+	loader := LoaderFunc[string, int](func(ctx context.Context, key string) (int, error) {
+		return len(key), nil
+	})
+	bulkLoader := BulkLoaderFunc[string, int](func(ctx context.Context, keys []string) (map[string]int, error) {
+		result := make(map[string]int, len(keys))
+		for _, key := range keys {
+			result[key] = len(key)
+		}
+		return result, nil
+	})
+	for i := 0; i < 20; i++ {
+		_, err := cache.Get(ctx, "a:"+strconv.Itoa(i), loader)
+		require.NoError(t, err)
+	}
+	cache.CleanUp()
+	cache.SetMaximum(100)
+	_, err := cache.BulkGet(ctx, []string{"b:1", "b:2", "a:100"}, bulkLoader)
+	require.NoError(t, err)
+	cache.GetIfPresent("b:1")
+	cache.Invalidate("b:2")
+	cache.CleanUp()
+
+	groups := counter.Groups()
+	require.Len(t, groups, 2)
+	a, b := groups["a"], groups["b"]
+	require.Equal(t, uint64(21), a.Misses)
+	require.Equal(t, uint64(21), a.LoadSuccesses)
+	require.Equal(t, uint64(10), a.OverflowEvictions)
+	require.Equal(t, uint64(2), b.Misses)
+	require.Equal(t, uint64(1), b.Hits)
+	require.Equal(t, uint64(1), b.LoadSuccesses)
+	require.Equal(t, uint64(1), b.Invalidations)
+
+	total := counter.Snapshot()
+	require.Equal(t, a.Hits+b.Hits, total.Hits)
+	require.Equal(t, a.Misses+b.Misses, total.Misses)
+	// the bulk load is recorded once for every group.
+	require.Equal(t, uint64(21), total.LoadSuccesses)
+	require.Equal(t, total, cache.Stats())
+}
+
+func TestCache_DeletionStats(t *testing.T) {
+	t.Parallel()
+
+	for _, maximum := range []int{0, 10} {
+		counter := stats.NewCounter()
+		fs := &fakeSource{}
+		cache := Must(&Options[int, int]{
+			MaximumSize:      maximum,
+			ExpiryCalculator: ExpiryWriting[int, int](time.Hour),
+			StatsRecorder:    counter,
+			Clock:            fs,
+		})
+
+		for i := 0; i < 10; i++ {
+			cache.Set(i, i)
+		}
+		cache.Set(0, 100)
+		cache.Set(1, 100)
+		cache.Invalidate(2)
+		cache.SetExpiresAfter(3, time.Minute)
+		fs.Sleep(2 * time.Minute)
+		cache.CleanUp()
+		if maximum > 0 {
+			for i := 10; i < 15; i++ {
+				cache.Set(i, i)
+			}
+			cache.CleanUp()
+		}
+
+		snapshot := counter.Snapshot()
+		require.Equal(t, uint64(2), snapshot.Replacements)
+		require.Equal(t, uint64(1), snapshot.Invalidations)
+		require.Equal(t, uint64(1), snapshot.ExpirationEvictions)
+		if maximum > 0 {
+			require.Equal(t, uint64(3), snapshot.OverflowEvictions)
+		} else {
+			require.Equal(t, uint64(0), snapshot.OverflowEvictions)
+		}
+		require.Equal(t, snapshot.OverflowEvictions+snapshot.ExpirationEvictions, snapshot.Evictions)
+		require.Equal(t, snapshot.Evictions, snapshot.EvictionWeight)
+	}
+}
+
 func TestCache_Ratio(t *testing.T) {
 	t.Parallel()
 
@@ -15,6 +15,7 @@
 package otter
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,15 +35,29 @@ type Clock interface {
 	// Tick returns a channel that delivers “ticks” of a clock at intervals.
 	//
 	// The cache uses this method only for proactive expiration and calls Tick(time.Second) in a separate goroutine.
+	// Snapshotter also uses this method to schedule periodic snapshots.
 	//
 	// By default, [time.Tick] is used.
 	Tick(duration time.Duration) <-chan time.Time
 }
 
+// Sleeper is an optional interface that a Clock can implement to control how the cache waits
+// between the retries of failed loads (see Options.RetryPolicy) and how [BatchLoader] waits for
+// the keys of a batch (see BatchOptions.Clock). For example, a fake Clock can advance its time
+// instead of waiting, so that the tests of retries and batching don't take real time.
+//
+// If the Clock doesn't implement Sleeper, the cache waits using a [time.Timer].
+type Sleeper interface {
+	// Sleep pauses the current goroutine for at least the duration or until ctx is done.
+	// It returns ctx.Err() if ctx is done before the duration elapses.
+	Sleep(ctx context.Context, duration time.Duration) error
+}
+
 type timeSource interface {
 	Clock
 	Init()
 	Sleep(duration time.Duration)
+	SleepContext(ctx context.Context, duration time.Duration) error
 	ProcessTick()
 }
 
@@ -61,12 +76,15 @@ func newTimeSource(clock Clock) timeSource {
 
 type customSource struct {
 	clock         Clock
+	sleeper       Sleeper
 	isInitialized atomic.Bool
 }
 
 func newCustomSource(clock Clock) *customSource {
+	sleeper, _ := clock.(Sleeper)
 	return &customSource{
-		clock: clock,
+		clock:   clock,
+		sleeper: sleeper,
 	}
 }
 
@@ -91,6 +109,13 @@ func (cs *customSource) Sleep(duration time.Duration) {
 	time.Sleep(duration)
 }
 
+func (cs *customSource) SleepContext(ctx context.Context, duration time.Duration) error {
+	if cs.sleeper != nil {
+		return cs.sleeper.Sleep(ctx, duration)
+	}
+	return sleepContext(ctx, duration)
+}
+
 func (cs *customSource) ProcessTick() {}
 
 type realSource struct {
@@ -128,6 +153,10 @@ func (c *realSource) Sleep(duration time.Duration) {
 	time.Sleep(duration)
 }
 
+func (c *realSource) SleepContext(ctx context.Context, duration time.Duration) error {
+	return sleepContext(ctx, duration)
+}
+
 func (c *realSource) ProcessTick() {}
 
 type fakeSource struct {
@@ -221,6 +250,14 @@ func (f *fakeSource) Sleep(d time.Duration) {
 	f.sleepWg.Wait()
 }
 
+func (f *fakeSource) SleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.Sleep(d)
+	return ctx.Err()
+}
+
 func (f *fakeSource) getNow() time.Time {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
@@ -230,3 +267,16 @@ func (f *fakeSource) getNow() time.Time {
 func (f *fakeSource) ProcessTick() {
 	f.tickWg.Done()
 }
+
+// sleepContext pauses the current goroutine for at least the duration or until the context is done.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	t := time.NewTimer(duration)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
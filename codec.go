@@ -0,0 +1,384 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Codec encodes and decodes cache entries during persistence.
+//
+// Codec is used by [SaveCacheTo] and [LoadCacheFrom] (and their file variants).
+// The entries are written and read as a stream, so the encoder and decoder may keep
+// state between entries (for example, type information).
+type Codec[K comparable, V any] interface {
+	// NewEncoder returns an EntryEncoder that writes entries to w.
+	NewEncoder(w io.Writer) EntryEncoder[K, V]
+	// NewDecoder returns an EntryDecoder that reads entries from r.
+	NewDecoder(r io.Reader) EntryDecoder[K, V]
+}
+
+// EntryEncoder writes cache entries to an output stream.
+type EntryEncoder[K comparable, V any] interface {
+	// Encode writes the encoding of entry to the stream.
+	//
+	// NOTE: the encoding must be completely written before Encode returns.
+	Encode(entry Entry[K, V]) error
+}
+
+// EntryDecoder reads cache entries from an input stream.
+type EntryDecoder[K comparable, V any] interface {
+	// Decode reads the next entry from the stream and stores it in the value pointed to by entry.
+	//
+	// Decode returns [io.EOF] when there are no more entries in the stream.
+	Decode(entry *Entry[K, V]) error
+}
+
+// GobCodec is a [Codec] that uses [encoding/gob].
+//
+// NOTE: interface-typed keys and values must be registered using [gob.Register].
+type GobCodec[K comparable, V any] struct{}
+
+// NewEncoder returns an EntryEncoder that writes gob-encoded entries to w.
+func (GobCodec[K, V]) NewEncoder(w io.Writer) EntryEncoder[K, V] {
+	return &gobEncoder[K, V]{enc: gob.NewEncoder(w)}
+}
+
+// NewDecoder returns an EntryDecoder that reads gob-encoded entries from r.
+func (GobCodec[K, V]) NewDecoder(r io.Reader) EntryDecoder[K, V] {
+	return &gobDecoder[K, V]{dec: gob.NewDecoder(r)}
+}
+
+type gobEncoder[K comparable, V any] struct {
+	enc *gob.Encoder
+}
+
+func (ge *gobEncoder[K, V]) Encode(entry Entry[K, V]) error {
+	return ge.enc.Encode(entry)
+}
+
+type gobDecoder[K comparable, V any] struct {
+	dec *gob.Decoder
+}
+
+func (gd *gobDecoder[K, V]) Decode(entry *Entry[K, V]) error {
+	return gd.dec.Decode(entry)
+}
+
+// JSONCodec is a [Codec] that uses [encoding/json].
+//
+// Every entry is written as a separate JSON object.
+type JSONCodec[K comparable, V any] struct{}
+
+// NewEncoder returns an EntryEncoder that writes JSON-encoded entries to w.
+func (JSONCodec[K, V]) NewEncoder(w io.Writer) EntryEncoder[K, V] {
+	return &jsonEncoder[K, V]{enc: json.NewEncoder(w)}
+}
+
+// NewDecoder returns an EntryDecoder that reads JSON-encoded entries from r.
+func (JSONCodec[K, V]) NewDecoder(r io.Reader) EntryDecoder[K, V] {
+	return &jsonDecoder[K, V]{dec: json.NewDecoder(r)}
+}
+
+type jsonEncoder[K comparable, V any] struct {
+	enc *json.Encoder
+}
+
+func (je *jsonEncoder[K, V]) Encode(entry Entry[K, V]) error {
+	return je.enc.Encode(entry)
+}
+
+type jsonDecoder[K comparable, V any] struct {
+	dec *json.Decoder
+}
+
+func (jd *jsonDecoder[K, V]) Decode(entry *Entry[K, V]) error {
+	return jd.dec.Decode(entry)
+}
+
+// BinaryCodec is a compact [Codec] that writes every entry as a sequence of length-prefixed fields.
+//
+// By default, BinaryCodec supports keys and values of boolean, integer, floating-point, string and []byte kinds
+// (including named types based on them), and types implementing [encoding.BinaryMarshaler] and
+// [encoding.BinaryUnmarshaler]. Other types (for example, protobuf messages) can be supported
+// by specifying the marshal and unmarshal functions.
+type BinaryCodec[K comparable, V any] struct {
+	// MarshalKey returns the binary representation of the key.
+	MarshalKey func(key K) ([]byte, error)
+	// UnmarshalKey restores the key from its binary representation.
+	//
+	// NOTE: data must not be retained after the function returns.
+	UnmarshalKey func(data []byte) (K, error)
+	// MarshalValue returns the binary representation of the value.
+	MarshalValue func(value V) ([]byte, error)
+	// UnmarshalValue restores the value from its binary representation.
+	//
+	// NOTE: data must not be retained after the function returns.
+	UnmarshalValue func(data []byte) (V, error)
+}
+
+// NewEncoder returns an EntryEncoder that writes binary-encoded entries to w.
+func (bc BinaryCodec[K, V]) NewEncoder(w io.Writer) EntryEncoder[K, V] {
+	be := &binaryEncoder[K, V]{
+		w:            w,
+		marshalKey:   bc.MarshalKey,
+		marshalValue: bc.MarshalValue,
+	}
+	if be.marshalKey == nil {
+		be.marshalKey = marshalBinary[K]
+	}
+	if be.marshalValue == nil {
+		be.marshalValue = marshalBinary[V]
+	}
+	return be
+}
+
+// NewDecoder returns an EntryDecoder that reads binary-encoded entries from r.
+func (bc BinaryCodec[K, V]) NewDecoder(r io.Reader) EntryDecoder[K, V] {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	bd := &binaryDecoder[K, V]{
+		r:              br,
+		unmarshalKey:   bc.UnmarshalKey,
+		unmarshalValue: bc.UnmarshalValue,
+	}
+	if bd.unmarshalKey == nil {
+		bd.unmarshalKey = unmarshalBinary[K]
+	}
+	if bd.unmarshalValue == nil {
+		bd.unmarshalValue = unmarshalBinary[V]
+	}
+	return bd
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+type binaryEncoder[K comparable, V any] struct {
+	w            io.Writer
+	buf          []byte
+	marshalKey   func(key K) ([]byte, error)
+	marshalValue func(value V) ([]byte, error)
+}
+
+func (be *binaryEncoder[K, V]) Encode(entry Entry[K, V]) error {
+	key, err := be.marshalKey(entry.Key)
+	if err != nil {
+		return fmt.Errorf("otter: marshal key: %w", err)
+	}
+	value, err := be.marshalValue(entry.Value)
+	if err != nil {
+		return fmt.Errorf("otter: marshal value: %w", err)
+	}
+
+	buf := be.buf[:0]
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	buf = binary.AppendUvarint(buf, uint64(entry.Weight))
+	buf = binary.AppendVarint(buf, entry.ExpiresAtNano)
+	buf = binary.AppendVarint(buf, entry.RefreshableAtNano)
+	buf = binary.AppendVarint(buf, entry.SnapshotAtNano)
+	be.buf = buf
+
+	_, err = be.w.Write(buf)
+	return err
+}
+
+type binaryDecoder[K comparable, V any] struct {
+	r              byteReader
+	buf            []byte
+	unmarshalKey   func(data []byte) (K, error)
+	unmarshalValue func(data []byte) (V, error)
+}
+
+func (bd *binaryDecoder[K, V]) Decode(entry *Entry[K, V]) error {
+	data, err := bd.readField()
+	if err != nil {
+		// the clean end of the stream can only happen before the first field of the entry.
+		return err
+	}
+	key, err := bd.unmarshalKey(data)
+	if err != nil {
+		return fmt.Errorf("otter: unmarshal key: %w", err)
+	}
+
+	data, err = bd.readField()
+	if err != nil {
+		return noEOF(err)
+	}
+	value, err := bd.unmarshalValue(data)
+	if err != nil {
+		return fmt.Errorf("otter: unmarshal value: %w", err)
+	}
+
+	weight, err := binary.ReadUvarint(bd.r)
+	if err != nil {
+		return noEOF(err)
+	}
+	if weight > math.MaxUint32 {
+		return fmt.Errorf("otter: invalid entry weight: %d", weight)
+	}
+	expiresAt, err := binary.ReadVarint(bd.r)
+	if err != nil {
+		return noEOF(err)
+	}
+	refreshableAt, err := binary.ReadVarint(bd.r)
+	if err != nil {
+		return noEOF(err)
+	}
+	snapshotAt, err := binary.ReadVarint(bd.r)
+	if err != nil {
+		return noEOF(err)
+	}
+
+	*entry = Entry[K, V]{
+		Key:               key,
+		Value:             value,
+		Weight:            uint32(weight),
+		ExpiresAtNano:     expiresAt,
+		RefreshableAtNano: refreshableAt,
+		SnapshotAtNano:    snapshotAt,
+	}
+	return nil
+}
+
+func (bd *binaryDecoder[K, V]) readField() ([]byte, error) {
+	length, err := binary.ReadUvarint(bd.r)
+	if err != nil {
+		return nil, err
+	}
+	if length > math.MaxInt32 {
+		return nil, fmt.Errorf("otter: invalid field length: %d", length)
+	}
+	if uint64(cap(bd.buf)) < length {
+		bd.buf = make([]byte, length)
+	}
+	bd.buf = bd.buf[:length]
+	if _, err := io.ReadFull(bd.r, bd.buf); err != nil {
+		return nil, noEOF(err)
+	}
+	return bd.buf, nil
+}
+
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func marshalBinary[T any](v T) ([]byte, error) {
+	if m, ok := any(v).(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	if m, ok := any(&v).(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+
+	rv := reflect.ValueOf(&v).Elem()
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.AppendVarint(nil, rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.AppendUvarint(nil, rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(rv.Float())), nil
+	case reflect.String:
+		return []byte(rv.String()), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("otter: binary codec does not support type %s", rv.Type())
+}
+
+func unmarshalBinary[T any](data []byte) (T, error) {
+	var v T
+	if u, ok := any(&v).(encoding.BinaryUnmarshaler); ok {
+		err := u.UnmarshalBinary(data)
+		return v, err
+	}
+
+	rv := reflect.ValueOf(&v).Elem()
+	// pointer types are allocated before unmarshaling.
+	if rv.Kind() == reflect.Pointer {
+		if u, ok := reflect.New(rv.Type().Elem()).Interface().(encoding.BinaryUnmarshaler); ok {
+			if err := u.UnmarshalBinary(data); err != nil {
+				return v, err
+			}
+			rv.Set(reflect.ValueOf(u))
+			return v, nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		if len(data) != 1 {
+			return v, fmt.Errorf("otter: invalid bool length: %d", len(data))
+		}
+		rv.SetBool(data[0] != 0)
+		return v, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, size := binary.Varint(data)
+		if size <= 0 || size != len(data) || rv.OverflowInt(n) {
+			return v, fmt.Errorf("otter: invalid %s", rv.Type())
+		}
+		rv.SetInt(n)
+		return v, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, size := binary.Uvarint(data)
+		if size <= 0 || size != len(data) || rv.OverflowUint(n) {
+			return v, fmt.Errorf("otter: invalid %s", rv.Type())
+		}
+		rv.SetUint(n)
+		return v, nil
+	case reflect.Float32, reflect.Float64:
+		if len(data) != 8 {
+			return v, fmt.Errorf("otter: invalid %s", rv.Type())
+		}
+		rv.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(data)))
+		return v, nil
+	case reflect.String:
+		rv.SetString(string(data))
+		return v, nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes(append([]byte(nil), data...))
+			return v, nil
+		}
+	}
+	return v, fmt.Errorf("otter: binary codec does not support type %s", rv.Type())
+}
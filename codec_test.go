@@ -0,0 +1,183 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type point struct {
+	X int32
+	Y int32
+}
+
+func (p point) MarshalBinary() ([]byte, error) {
+	b := binary.BigEndian.AppendUint32(nil, uint32(p.X))
+	return binary.BigEndian.AppendUint32(b, uint32(p.Y)), nil
+}
+
+func (p *point) UnmarshalBinary(data []byte) error {
+	if len(data) != 8 {
+		return errors.New("invalid point")
+	}
+	p.X = int32(binary.BigEndian.Uint32(data))
+	p.Y = int32(binary.BigEndian.Uint32(data[4:]))
+	return nil
+}
+
+type userID string
+
+func testCodecRoundTrip[K comparable, V any](t *testing.T, codec Codec[K, V], entries []Entry[K, V]) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := codec.NewEncoder(&buf)
+	for _, e := range entries {
+		require.NoError(t, enc.Encode(e))
+	}
+
+	dec := codec.NewDecoder(&buf)
+	for _, e := range entries {
+		var got Entry[K, V]
+		require.NoError(t, dec.Decode(&got))
+		require.Equal(t, e, got)
+	}
+	var e Entry[K, V]
+	require.ErrorIs(t, dec.Decode(&e), io.EOF)
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	entries := make([]Entry[string, int], 0, 10)
+	for i := 0; i < 10; i++ {
+		entries = append(entries, Entry[string, int]{
+			Key:               strconv.Itoa(i),
+			Value:             -i,
+			Weight:            uint32(i),
+			ExpiresAtNano:     unreachableExpiresAt,
+			RefreshableAtNano: int64(i) * 1000,
+			SnapshotAtNano:    int64(i),
+		})
+	}
+
+	t.Run("gob", func(t *testing.T) {
+		t.Parallel()
+
+		testCodecRoundTrip[string, int](t, GobCodec[string, int]{}, entries)
+	})
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		testCodecRoundTrip[string, int](t, JSONCodec[string, int]{}, entries)
+	})
+	t.Run("binary", func(t *testing.T) {
+		t.Parallel()
+
+		testCodecRoundTrip[string, int](t, BinaryCodec[string, int]{}, entries)
+	})
+}
+
+func TestBinaryCodec_Types(t *testing.T) {
+	t.Parallel()
+
+	t.Run("basic", func(t *testing.T) {
+		t.Parallel()
+
+		testCodecRoundTrip[userID, bool](t, BinaryCodec[userID, bool]{}, []Entry[userID, bool]{
+			{Key: "a", Value: true, Weight: 1},
+			{Key: "", Value: false, Weight: 1},
+		})
+		testCodecRoundTrip[uint16, float64](t, BinaryCodec[uint16, float64]{}, []Entry[uint16, float64]{
+			{Key: 65535, Value: 1.5, Weight: 1},
+			{Key: 0, Value: -0.25, Weight: 1},
+		})
+		testCodecRoundTrip[int8, []byte](t, BinaryCodec[int8, []byte]{}, []Entry[int8, []byte]{
+			{Key: -128, Value: []byte("hello"), Weight: 1},
+			{Key: 127, Value: nil, Weight: 1},
+		})
+	})
+	t.Run("binary_marshaler", func(t *testing.T) {
+		t.Parallel()
+
+		testCodecRoundTrip[point, *point](t, BinaryCodec[point, *point]{}, []Entry[point, *point]{
+			{Key: point{X: 1, Y: 2}, Value: &point{X: -3, Y: 4}, Weight: 1},
+		})
+	})
+	t.Run("custom", func(t *testing.T) {
+		t.Parallel()
+
+		codec := BinaryCodec[int, []int]{
+			MarshalValue: func(value []int) ([]byte, error) {
+				var b []byte
+				for _, v := range value {
+					b = binary.AppendVarint(b, int64(v))
+				}
+				return b, nil
+			},
+			UnmarshalValue: func(data []byte) ([]int, error) {
+				var res []int
+				for len(data) > 0 {
+					v, n := binary.Varint(data)
+					if n <= 0 {
+						return nil, errors.New("invalid varint")
+					}
+					res = append(res, int(v))
+					data = data[n:]
+				}
+				return res, nil
+			},
+		}
+		testCodecRoundTrip[int, []int](t, codec, []Entry[int, []int]{
+			{Key: 1, Value: []int{1, -2, 3}, Weight: 1},
+		})
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		enc := BinaryCodec[int, map[int]int]{}.NewEncoder(io.Discard)
+		err := enc.Encode(Entry[int, map[int]int]{Key: 1, Value: map[int]int{}})
+		require.ErrorContains(t, err, "binary codec does not support type map[int]int")
+	})
+	t.Run("overflow", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := BinaryCodec[int, int]{}.NewEncoder(&buf).Encode(Entry[int, int]{Key: 1000, Value: 1})
+		require.NoError(t, err)
+
+		var e Entry[int8, int]
+		err = BinaryCodec[int8, int]{}.NewDecoder(&buf).Decode(&e)
+		require.ErrorContains(t, err, "invalid int8")
+	})
+	t.Run("truncated", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := BinaryCodec[string, string]{}.NewEncoder(&buf).Encode(Entry[string, string]{Key: "key", Value: "value"})
+		require.NoError(t, err)
+
+		var e Entry[string, string]
+		err = BinaryCodec[string, string]{}.NewDecoder(bytes.NewReader(buf.Bytes()[:7])).Decode(&e)
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
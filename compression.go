@@ -0,0 +1,128 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// Compressor compresses and decompresses the snapshot data during persistence.
+//
+// The name of the Compressor is stored in the snapshot header, which allows [LoadCacheFrom]
+// to detect the compression automatically.
+type Compressor interface {
+	// Name returns the unique name of the compression algorithm.
+	//
+	// The name must not be longer than 255 bytes.
+	Name() string
+	// NewWriter returns a writer that compresses data and writes it to w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader that decompresses data read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCompressor is a [Compressor] that uses [compress/gzip].
+type GzipCompressor struct {
+	// Level is the compression level. The zero value means gzip.DefaultCompression,
+	// so gzip.NoCompression can't be selected. To save the snapshot uncompressed, don't specify a Compressor.
+	Level int
+}
+
+// Name returns "gzip".
+func (GzipCompressor) Name() string {
+	return "gzip"
+}
+
+// NewWriter returns a gzip writer.
+func (gc GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, compressionLevel(gc.Level))
+}
+
+// NewReader returns a gzip reader.
+func (GzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// ZlibCompressor is a [Compressor] that uses [compress/zlib].
+type ZlibCompressor struct {
+	// Level is the compression level. The zero value means zlib.DefaultCompression,
+	// so zlib.NoCompression can't be selected. To save the snapshot uncompressed, don't specify a Compressor.
+	Level int
+}
+
+// Name returns "zlib".
+func (ZlibCompressor) Name() string {
+	return "zlib"
+}
+
+// NewWriter returns a zlib writer.
+func (zc ZlibCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, compressionLevel(zc.Level))
+}
+
+// NewReader returns a zlib reader.
+func (ZlibCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+// FlateCompressor is a [Compressor] that uses [compress/flate].
+type FlateCompressor struct {
+	// Level is the compression level. The zero value means flate.DefaultCompression,
+	// so flate.NoCompression can't be selected. To save the snapshot uncompressed, don't specify a Compressor.
+	Level int
+}
+
+// Name returns "flate".
+func (FlateCompressor) Name() string {
+	return "flate"
+}
+
+// NewWriter returns a flate writer.
+func (fc FlateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, compressionLevel(fc.Level))
+}
+
+// NewReader returns a flate reader.
+func (FlateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+func compressionLevel(level int) int {
+	if level == 0 {
+		return flate.DefaultCompression
+	}
+	return level
+}
+
+var builtinCompressors = []Compressor{
+	GzipCompressor{},
+	ZlibCompressor{},
+	FlateCompressor{},
+}
+
+// findCompressor returns the compressor with the given name.
+func findCompressor(name string, compressors []Compressor) (Compressor, bool) {
+	for _, cs := range [][]Compressor{compressors, builtinCompressors} {
+		for _, c := range cs {
+			if c.Name() == name {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
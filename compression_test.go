@@ -0,0 +1,119 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+type identityCompressor struct{}
+
+func (identityCompressor) Name() string {
+	return "identity"
+}
+
+func (identityCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{Writer: w}, nil
+}
+
+func (identityCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+func TestCompressor(t *testing.T) {
+	t.Parallel()
+
+	const maximum = 100
+	c := Must(&Options[int, string]{
+		MaximumSize: maximum,
+	})
+	for i := 0; i < maximum; i++ {
+		c.Set(i, strings.Repeat("a", i))
+	}
+
+	var plain bytes.Buffer
+	require.NoError(t, SaveCacheTo(c, &plain))
+
+	compressors := []Compressor{
+		GzipCompressor{},
+		GzipCompressor{Level: gzip.BestSpeed},
+		ZlibCompressor{},
+		FlateCompressor{Level: gzip.BestCompression},
+		identityCompressor{},
+	}
+	for _, compressor := range compressors {
+		t.Run(compressor.Name(), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			err := SaveCacheTo(c, &buf, &SaveOptions[int, string]{
+				Compressor: compressor,
+			})
+			require.NoError(t, err)
+			if compressor.Name() != "identity" {
+				require.Less(t, buf.Len(), plain.Len())
+			}
+
+			loaded := Must(&Options[int, string]{
+				MaximumSize: maximum,
+			})
+			err = LoadCacheFrom(loaded, bytes.NewReader(buf.Bytes()), &LoadOptions[int, string]{
+				Compressors: []Compressor{identityCompressor{}},
+			})
+			require.NoError(t, err)
+			require.Equal(t, maximum, loaded.EstimatedSize())
+			for i := 0; i < maximum; i++ {
+				v, ok := loaded.GetIfPresent(i)
+				require.True(t, ok)
+				require.Equal(t, strings.Repeat("a", i), v)
+			}
+
+			// corrupt the compressed data
+			data := bytes.Clone(buf.Bytes())
+			data[len(data)/2] ^= 0xff
+			err = LoadCacheFrom(Must(&Options[int, string]{}), bytes.NewReader(data), &LoadOptions[int, string]{
+				Compressors: []Compressor{identityCompressor{}},
+			})
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := SaveCacheTo(c, &buf, &SaveOptions[int, string]{
+			Compressor: identityCompressor{},
+		})
+		require.NoError(t, err)
+
+		err = LoadCacheFrom(Must(&Options[int, string]{}), &buf)
+		require.ErrorIs(t, err, ErrUnknownSnapshotCompression)
+	})
+}
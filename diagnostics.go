@@ -0,0 +1,172 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Diagnostics is a snapshot of the internal state of the cache that helps to debug capacity and latency issues.
+//
+// The fields are implementation details and may change between versions of otter.
+type Diagnostics struct {
+	// EstimatedSize is the approximate number of entries in the cache.
+	EstimatedSize int `json:"estimated_size"`
+	// HashTableCapacity is the number of entries that the hash table can hold without growing.
+	HashTableCapacity int `json:"hash_table_capacity"`
+	// ReadBufferLen is the number of reads that are waiting to be applied to the eviction policy.
+	ReadBufferLen int `json:"read_buffer_len"`
+	// WriteBufferLen is the number of writes that are waiting to be applied to the eviction and expiration policies.
+	WriteBufferLen int `json:"write_buffer_len"`
+	// KnownAbsentSize is the approximate number of known absences (see Options.NegativeTTL).
+	KnownAbsentSize int `json:"known_absent_size,omitempty"`
+	// KnownAbsentWeightedSize is the weight of the known absences if Options.NegativeMaximumWeight is specified.
+	KnownAbsentWeightedSize uint64 `json:"known_absent_weighted_size,omitempty"`
+	// StaleSize is the approximate number of evicted expired entries that are within their grace period
+	// (see Options.GraceCalculator).
+	StaleSize int `json:"stale_size,omitempty"`
+	// Eviction is the state of the eviction policy. It is nil if the cache is unbounded.
+	Eviction *EvictionDiagnostics `json:"eviction,omitempty"`
+	// TimerWheel is the occupancy of the levels of the timer wheel used for expiration,
+	// ordered from the shortest to the longest span. It is nil if the entries do not expire.
+	TimerWheel []TimerWheelLevel `json:"timer_wheel,omitempty"`
+}
+
+// EvictionDiagnostics is the state of the W-TinyLFU eviction policy.
+//
+// The sizes are weighted if the cache is weighted.
+type EvictionDiagnostics struct {
+	// Maximum is the maximum size of the cache.
+	Maximum uint64 `json:"maximum"`
+	// WeightedSize is the size of all entries in the eviction policy.
+	WeightedSize uint64 `json:"weighted_size"`
+	// WindowMaximum is the maximum size of the window space, chosen by the hill climber.
+	WindowMaximum uint64 `json:"window_maximum"`
+	// WindowWeightedSize is the size of the window space.
+	WindowWeightedSize uint64 `json:"window_weighted_size"`
+	// WindowLen is the number of entries in the window space.
+	WindowLen int `json:"window_len"`
+	// ProbationWeightedSize is the size of the main's probation space.
+	ProbationWeightedSize uint64 `json:"probation_weighted_size"`
+	// ProbationLen is the number of entries in the main's probation space.
+	ProbationLen int `json:"probation_len"`
+	// ProtectedMaximum is the maximum size of the main's protected space.
+	ProtectedMaximum uint64 `json:"protected_maximum"`
+	// ProtectedWeightedSize is the size of the main's protected space.
+	ProtectedWeightedSize uint64 `json:"protected_weighted_size"`
+	// ProtectedLen is the number of entries in the main's protected space.
+	ProtectedLen int `json:"protected_len"`
+	// StepSize is the current step size of the hill climber.
+	StepSize float64 `json:"step_size"`
+	// PreviousSampleHitRate is the hit rate of the previous sample, used by the hill climber.
+	PreviousSampleHitRate float64 `json:"previous_sample_hit_rate"`
+	// HitsInSample is the number of hits in the current sample.
+	HitsInSample uint64 `json:"hits_in_sample"`
+	// MissesInSample is the number of misses in the current sample.
+	MissesInSample uint64 `json:"misses_in_sample"`
+	// SketchCounters is the number of 64-bit words of the frequency sketch. It is zero until
+	// the sketch is initialized, which happens when the cache is half full or InitialCapacity is specified.
+	SketchCounters int `json:"sketch_counters"`
+	// SketchSampleSize is the number of increments after which the sketch's counters are halved.
+	SketchSampleSize uint64 `json:"sketch_sample_size"`
+	// SketchSize is the number of increments since the last halving of the sketch's counters.
+	SketchSize uint64 `json:"sketch_size"`
+}
+
+// TimerWheelLevel is the occupancy of a level of the timer wheel.
+type TimerWheelLevel struct {
+	// Span is the duration covered by a bucket of the level.
+	Span time.Duration `json:"span"`
+	// Buckets is the number of buckets of the level.
+	Buckets int `json:"buckets"`
+	// Len is the number of entries scheduled to expire in the level.
+	Len int `json:"len"`
+}
+
+// DiagnosticsHandler returns an [http.Handler] that renders the result of Cache.Diagnostics as JSON.
+func DiagnosticsHandler[K comparable, V any](c *Cache[K, V]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(c.Diagnostics()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Diagnostics returns a snapshot of the internal state of the cache.
+//
+// WARNING: The snapshot is taken within the eviction policy's exclusive lock and counting the entries of
+// the timer wheel takes O(n) time, so Diagnostics should be used only for debugging.
+func (c *cache[K, V]) Diagnostics() Diagnostics {
+	d := Diagnostics{
+		EstimatedSize:     c.EstimatedSize(),
+		HashTableCapacity: c.hashmap.Capacity(),
+	}
+	if c.negative != nil {
+		d.KnownAbsentSize = c.negative.EstimatedSize()
+		d.KnownAbsentWeightedSize = c.negative.WeightedSize()
+	}
+	if c.stale != nil {
+		d.StaleSize = c.stale.EstimatedSize()
+	}
+	if !c.withMaintenance {
+		return d
+	}
+
+	c.evictionMutex.Lock()
+	defer c.evictionMutex.Unlock()
+
+	d.ReadBufferLen = c.readBuffer.Len()
+	//nolint:gosec // there is no overflow
+	d.WriteBufferLen = int(c.writeBuffer.Size())
+	if c.withEviction {
+		p := c.evictionPolicy
+		d.Eviction = &EvictionDiagnostics{
+			Maximum:               p.maximum,
+			WeightedSize:          p.weightedSize,
+			WindowMaximum:         p.windowMaximum,
+			WindowWeightedSize:    p.windowWeightedSize,
+			WindowLen:             p.window.Len(),
+			ProbationWeightedSize: p.weightedSize - p.windowWeightedSize - p.mainProtectedWeightedSize,
+			ProbationLen:          p.probation.Len(),
+			ProtectedMaximum:      p.mainProtectedMaximum,
+			ProtectedWeightedSize: p.mainProtectedWeightedSize,
+			ProtectedLen:          p.protected.Len(),
+			StepSize:              p.stepSize,
+			PreviousSampleHitRate: p.previousSampleHitRate,
+			HitsInSample:          p.hitsInSample,
+			MissesInSample:        p.missesInSample,
+			SketchCounters:        len(p.sketch.table),
+			SketchSampleSize:      p.sketch.sampleSize,
+			SketchSize:            p.sketch.size,
+		}
+	}
+	if c.withExpiration {
+		levels := c.expirationPolicy.Levels()
+		d.TimerWheel = make([]TimerWheelLevel, 0, len(levels))
+		for _, l := range levels {
+			d.TimerWheel = append(d.TimerWheel, TimerWheelLevel{
+				Span:    l.Span,
+				Buckets: l.Buckets,
+				Len:     l.Len,
+			})
+		}
+	}
+	return d
+}
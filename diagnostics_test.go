@@ -0,0 +1,110 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Diagnostics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unbounded", func(t *testing.T) {
+		t.Parallel()
+
+		c := Must[int, int](&Options[int, int]{})
+		for i := 0; i < 1000; i++ {
+			c.Set(i, i)
+		}
+
+		d := c.Diagnostics()
+		require.Equal(t, 1000, d.EstimatedSize)
+		require.GreaterOrEqual(t, d.HashTableCapacity, 1000)
+		require.Nil(t, d.Eviction)
+		require.Nil(t, d.TimerWheel)
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		t.Parallel()
+
+		mc := newManualClock()
+		c := Must(&Options[int, int]{
+			MaximumSize:      100,
+			ExpiryCalculator: ExpiryWriting[int, int](time.Minute),
+			Clock:            mc,
+		})
+		for i := 0; i < 200; i++ {
+			c.Set(i, i)
+		}
+		for i := 150; i < 200; i++ {
+			c.GetIfPresent(i)
+		}
+		c.CleanUp()
+
+		d := c.Diagnostics()
+		require.Equal(t, 100, d.EstimatedSize)
+		require.Zero(t, d.ReadBufferLen)
+		require.Zero(t, d.WriteBufferLen)
+
+		e := d.Eviction
+		require.NotNil(t, e)
+		require.Equal(t, uint64(100), e.Maximum)
+		require.Equal(t, uint64(100), e.WeightedSize)
+		require.Equal(t, uint64(1), e.WindowMaximum)
+		require.Equal(t, 100, e.WindowLen+e.ProbationLen+e.ProtectedLen)
+		require.Equal(t, e.WeightedSize, e.WindowWeightedSize+e.ProbationWeightedSize+e.ProtectedWeightedSize)
+		require.Equal(t, uint64(e.ProtectedLen), e.ProtectedWeightedSize)
+		require.Equal(t, uint64(79), e.ProtectedMaximum)
+		require.Equal(t, 128, e.SketchCounters)
+		require.Equal(t, uint64(1000), e.SketchSampleSize)
+
+		require.Len(t, d.TimerWheel, 5)
+		total := 0
+		for _, l := range d.TimerWheel {
+			total += l.Len
+		}
+		require.Equal(t, 100, total)
+	})
+}
+
+func TestDiagnosticsHandler(t *testing.T) {
+	t.Parallel()
+
+	c := Must(&Options[int, int]{
+		MaximumSize: 10,
+	})
+	c.Set(1, 1)
+	c.CleanUp()
+
+	rec := httptest.NewRecorder()
+	DiagnosticsHandler(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/otter", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &m))
+	require.Equal(t, float64(1), m["estimated_size"])
+	require.NotContains(t, m, "timer_wheel")
+	eviction, ok := m["eviction"].(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, float64(10), eviction["maximum"])
+	require.Equal(t, float64(1), eviction["weighted_size"])
+}
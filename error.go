@@ -2,6 +2,7 @@ package otter
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"runtime/debug"
 )
@@ -14,8 +15,42 @@ const (
 	// NOTE: this only applies to Cache.Get/Cache.Refresh/Loader.Load/Loader.Reload. For Cache.BulkGet/Cache.BulkRefresh,
 	// this works implicitly if you return a map without the key.
 	ErrNotFound strError = "otter: the entry was not found in the data source"
+	// ErrStale is returned by Cache.Get and Cache.BulkGet along with an expired value, when loading
+	// the new value failed and the expired entry is still within its grace period (see Options.GraceCalculator).
+	// The returned error also wraps the error of the loader.
+	ErrStale strError = "otter: the value is stale because loading its new value failed"
 )
 
+// ErrKnownAbsent is returned by Cache.Get if the loader recently reported that the entry was not found in
+// the data source and the result was cached (see Options.NegativeTTL). It wraps ErrNotFound,
+// so errors.Is(err, ErrNotFound) reports true for it.
+var ErrKnownAbsent error = knownAbsentError{}
+
+type knownAbsentError struct{}
+
+func (knownAbsentError) Error() string {
+	return "otter: the entry is known to be absent from the data source"
+}
+
+func (knownAbsentError) Unwrap() error {
+	return ErrNotFound
+}
+
+// ErrLoadTimeout is returned by Cache.Get and Cache.BulkGet, and reported by the refreshes, if the loader
+// didn't finish within Options.LoadTimeout or Options.RefreshTimeout. It wraps context.DeadlineExceeded,
+// so errors.Is(err, context.DeadlineExceeded) reports true for it.
+var ErrLoadTimeout error = loadTimeoutError{}
+
+type loadTimeoutError struct{}
+
+func (loadTimeoutError) Error() string {
+	return "otter: the loader didn't finish within the timeout"
+}
+
+func (loadTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
 // strError allows declaring errors as constants.
 type strError string
 
@@ -0,0 +1,90 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"expvar"
+	"math"
+)
+
+// expvarStats is the JSON representation of the cache statistics published by [Expvar].
+type expvarStats struct {
+	Hits                uint64  `json:"hits"`
+	Misses              uint64  `json:"misses"`
+	HitRatio            float64 `json:"hit_ratio"`
+	Evictions           uint64  `json:"evictions"`
+	EvictionWeight      uint64  `json:"eviction_weight"`
+	OverflowEvictions   uint64  `json:"overflow_evictions"`
+	ExpirationEvictions uint64  `json:"expiration_evictions"`
+	Invalidations       uint64  `json:"invalidations"`
+	Replacements        uint64  `json:"replacements"`
+	LoadSuccesses       uint64  `json:"load_successes"`
+	LoadFailures        uint64  `json:"load_failures"`
+	TotalLoadTime       float64 `json:"total_load_time_seconds"`
+	RefreshSuccesses    uint64  `json:"refresh_successes"`
+	RefreshFailures     uint64  `json:"refresh_failures"`
+	TotalRefreshTime    float64 `json:"total_refresh_time_seconds"`
+	StaleServes         uint64  `json:"stale_serves"`
+	CoalescedLoads      uint64  `json:"coalesced_loads"`
+	EstimatedSize       int     `json:"estimated_size"`
+	WeightedSize        uint64  `json:"weighted_size,omitempty"`
+	Maximum             uint64  `json:"maximum,omitempty"`
+}
+
+// Expvar returns an [expvar.Var] that renders the statistics, EstimatedSize, WeightedSize and GetMaximum
+// of the cache as a JSON object.
+//
+// The values are collected lazily every time the variable is read, so it doesn't slow down the cache.
+// The weighted size is omitted if the cache isn't weighted, and the maximum is omitted if the cache is unbounded.
+func Expvar[K comparable, V any](c *Cache[K, V]) expvar.Var {
+	return expvar.Func(func() any {
+		s := c.Stats()
+		v := expvarStats{
+			Hits:                s.Hits,
+			Misses:              s.Misses,
+			HitRatio:            s.HitRatio(),
+			Evictions:           s.Evictions,
+			EvictionWeight:      s.EvictionWeight,
+			OverflowEvictions:   s.OverflowEvictions,
+			ExpirationEvictions: s.ExpirationEvictions,
+			Invalidations:       s.Invalidations,
+			Replacements:        s.Replacements,
+			LoadSuccesses:       s.LoadSuccesses,
+			LoadFailures:        s.LoadFailures,
+			TotalLoadTime:       s.TotalLoadTime.Seconds(),
+			RefreshSuccesses:    s.RefreshSuccesses,
+			RefreshFailures:     s.RefreshFailures,
+			TotalRefreshTime:    s.TotalRefreshTime.Seconds(),
+			StaleServes:         s.StaleServes,
+			CoalescedLoads:      s.CoalescedLoads,
+			EstimatedSize:       c.EstimatedSize(),
+		}
+		if c.IsWeighted() {
+			v.WeightedSize = c.WeightedSize()
+		}
+		if maximum := c.GetMaximum(); maximum != math.MaxUint64 {
+			v.Maximum = maximum
+		}
+		return v
+	})
+}
+
+// PublishExpvar publishes the statistics of the cache returned by [Expvar] under the specified name,
+// so they are served by the /debug/vars handler of the expvar package.
+//
+// Like [expvar.Publish], PublishExpvar panics if the name is already registered.
+func PublishExpvar[K comparable, V any](name string, c *Cache[K, V]) {
+	expvar.Publish(name, Expvar(c))
+}
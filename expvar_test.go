@@ -0,0 +1,75 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maypok86/otter/v2/stats"
+)
+
+func TestExpvar(t *testing.T) {
+	t.Parallel()
+
+	c := Must(&Options[int, int]{
+		MaximumWeight: 100,
+		Weigher: func(key int, value int) uint32 {
+			return 10
+		},
+		StatsRecorder: stats.NewCounter(),
+	})
+	PublishExpvar("otter_test_weighted", c)
+	require.Panics(t, func() {
+		PublishExpvar("otter_test_weighted", c)
+	})
+
+	read := func(name string) map[string]any {
+		t.Helper()
+
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &m))
+		return m
+	}
+
+	// the values are collected on every read.
+	require.Equal(t, float64(0), read("otter_test_weighted")["estimated_size"])
+	for i := 0; i < 20; i++ {
+		c.Set(i, i)
+		c.GetIfPresent(i)
+	}
+	c.GetIfPresent(100)
+	c.CleanUp()
+
+	m := read("otter_test_weighted")
+	require.Equal(t, float64(20), m["hits"])
+	require.Equal(t, float64(1), m["misses"])
+	require.Equal(t, float64(10), m["evictions"])
+	require.Equal(t, float64(10), m["overflow_evictions"])
+	require.Equal(t, float64(10), m["estimated_size"])
+	require.Equal(t, float64(100), m["weighted_size"])
+	require.Equal(t, float64(100), m["maximum"])
+
+	unbounded := Must(&Options[int, int]{})
+	unbounded.Set(1, 1)
+	PublishExpvar("otter_test_unbounded", unbounded)
+	m = read("otter_test_unbounded")
+	require.Equal(t, float64(1), m["estimated_size"])
+	require.NotContains(t, m, "weighted_size")
+	require.NotContains(t, m, "maximum")
+}
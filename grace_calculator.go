@@ -0,0 +1,54 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"time"
+)
+
+// GraceCalculator calculates how long expired cache entries are retained to be served when loading
+// their new values fails, similar to the stale-if-error directive of HTTP caching.
+type GraceCalculator[K comparable, V any] interface {
+	// GraceAfterExpiry specifies that the expired entry may be returned by Cache.Get and Cache.BulkGet
+	// along with ErrStale until the duration has elapsed after the entry's expiration, if the loader fails.
+	// To indicate no grace period, an entry may be given a non-positive duration.
+	//
+	// NOTE: entry.ExpiresAtNano is the time when the entry expired.
+	GraceAfterExpiry(entry Entry[K, V]) time.Duration
+}
+
+type varGrace[K comparable, V any] struct {
+	f func(entry Entry[K, V]) time.Duration
+}
+
+func (g *varGrace[K, V]) GraceAfterExpiry(entry Entry[K, V]) time.Duration {
+	return g.f(entry)
+}
+
+// GraceExpiring returns a [GraceCalculator] that specifies that the expired entry may be served
+// when loading its new value fails until the duration has elapsed after the entry's expiration.
+func GraceExpiring[K comparable, V any](duration time.Duration) GraceCalculator[K, V] {
+	return GraceExpiringFunc(func(entry Entry[K, V]) time.Duration {
+		return duration
+	})
+}
+
+// GraceExpiringFunc returns a [GraceCalculator] that specifies that the expired entry may be served
+// when loading its new value fails until the duration has elapsed after the entry's expiration.
+func GraceExpiringFunc[K comparable, V any](f func(entry Entry[K, V]) time.Duration) GraceCalculator[K, V] {
+	return &varGrace[K, V]{
+		f: f,
+	}
+}
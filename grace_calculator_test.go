@@ -0,0 +1,39 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarGrace(t *testing.T) {
+	t.Parallel()
+
+	e := Entry[int, int]{
+		Key:            1,
+		Value:          2,
+		Weight:         1,
+		ExpiresAtNano:  100,
+		SnapshotAtNano: 150,
+	}
+	g := GraceExpiringFunc(func(entry Entry[int, int]) time.Duration {
+		return time.Duration(int64(entry.Value) * entry.ExpiresAtNano)
+	})
+	require.Equal(t, time.Duration(int64(e.Value)*e.ExpiresAtNano), g.GraceAfterExpiry(e))
+	require.Equal(t, time.Minute, GraceExpiring[int, int](time.Minute).GraceAfterExpiry(e))
+}
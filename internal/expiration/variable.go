@@ -65,6 +65,36 @@ func NewVariable[K comparable, V any](nodeManager *node.Manager[K, V]) *Variable
 	}
 }
 
+// Level describes the occupancy of a level of the timer wheel.
+type Level struct {
+	// Span is the duration covered by a bucket of the level.
+	Span time.Duration
+	// Buckets is the number of buckets of the level.
+	Buckets int
+	// Len is the number of timer events scheduled in the level.
+	Len int
+}
+
+// Levels returns the occupancy of every level of the timer wheel. It takes O(n) time.
+func (v *Variable[K, V]) Levels() []Level {
+	levels := make([]Level, 0, len(v.wheel))
+	for i, level := range v.wheel {
+		count := 0
+		for _, root := range level {
+			for n := root.NextExp(); !node.Equals(n, root); n = n.NextExp() {
+				count++
+			}
+		}
+		levels = append(levels, Level{
+			//nolint:gosec // there is no overflow
+			Span:    time.Duration(spans[i]),
+			Buckets: len(level),
+			Len:     count,
+		})
+	}
+	return levels
+}
+
 // findBucket determines the bucket that the timer event should be added to.
 func (v *Variable[K, V]) findBucket(expiration uint64) node.Node[K, V] {
 	duration := expiration - v.time
@@ -155,3 +155,36 @@ func TestVariable_DeleteExpired(t *testing.T) {
 	keys = append(keys, "k7")
 	match(t, expired, keys)
 }
+
+func TestVariable_Levels(t *testing.T) {
+	t.Parallel()
+
+	nm := node.NewManager[string, string](node.Config{
+		WithExpiration: true,
+	})
+	nodes := []node.Node[string, string]{
+		nm.Create("k1", "", getTestExp(1), 0, 1),
+		nm.Create("k2", "", getTestExp(2), 0, 1),
+		nm.Create("k3", "", getTestExp(69), 0, 1),
+		nm.Create("k4", "", getTestExp(4399), 0, 1),
+	}
+	v := NewVariable(nm)
+	for _, n := range nodes {
+		v.Add(n)
+	}
+	v.Delete(nodes[1])
+
+	levels := v.Levels()
+	if len(levels) != len(buckets) {
+		t.Fatalf("Not valid number of levels: %d", len(levels))
+	}
+	wantLens := []int{1, 1, 1, 0, 0}
+	for i, level := range levels {
+		if level.Len != wantLens[i] {
+			t.Fatalf("Not valid length of level %d: %d, want %d", i, level.Len, wantLens[i])
+		}
+		if uint64(level.Buckets) != buckets[i] || uint64(level.Span) != spans[i] {
+			t.Fatalf("Not valid level %d: %+v", i, level)
+		}
+	}
+}
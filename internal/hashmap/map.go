@@ -97,6 +97,7 @@ type Map[K comparable, V any, N mapNode[K, V]] struct {
 	resizeCond   sync.Cond                   // used to wake up resize waiters (concurrent modifications)
 	table        atomic.Pointer[mapTable[K]] // *mapTable
 	nodeManager  mapNodeManager[K, V, N]
+	onResize     func(oldCapacity, newCapacity int)
 	minTableLen  int
 }
 
@@ -142,6 +143,18 @@ func New[K comparable, V any, N mapNode[K, V]](nodeManager mapNodeManager[K, V,
 	return newMap[K, V, N](nodeManager, defaultMinMapTableLen*nodesPerMapBucket)
 }
 
+// Capacity returns the number of nodes that the current table can hold without chaining.
+func (m *Map[K, V, N]) Capacity() int {
+	return len(m.table.Load().buckets) * nodesPerMapBucket
+}
+
+// SetOnResize sets the function that is called after every resize of the table with its old and new capacity.
+//
+// SetOnResize must be called before the map is used.
+func (m *Map[K, V, N]) SetOnResize(onResize func(oldCapacity, newCapacity int)) {
+	m.onResize = onResize
+}
+
 func newMap[K comparable, V any, N mapNode[K, V]](nodeManager mapNodeManager[K, V, N], sizeHint int) *Map[K, V, N] {
 	m := &Map[K, V, N]{
 		nodeManager: nodeManager,
@@ -442,6 +455,9 @@ func (m *Map[K, V, N]) resize(knownTable *mapTable[K], hint mapResizeHint) {
 	m.resizing.Store(false)
 	m.resizeCond.Broadcast()
 	m.resizeMu.Unlock()
+	if m.onResize != nil {
+		m.onResize(tableLen*nodesPerMapBucket, len(newTable.buckets)*nodesPerMapBucket)
+	}
 }
 
 func (m *Map[K, V, N]) copyBucketWithDestLock(b *bucketPadded, destTable *mapTable[K]) (copied int) {
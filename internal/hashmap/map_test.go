@@ -569,6 +569,45 @@ func TestMapClear(t *testing.T) {
 	}
 }
 
+func TestMap_OnResize(t *testing.T) {
+	t.Parallel()
+
+	const numNodes = 1000
+	nm := testNodeManager[int, int]()
+	m := New(nm)
+	minCapacity := defaultMinMapTableLen * nodesPerMapBucket
+	var resizes [][2]int
+	m.SetOnResize(func(oldCapacity, newCapacity int) {
+		resizes = append(resizes, [2]int{oldCapacity, newCapacity})
+	})
+	for i := 0; i < numNodes; i++ {
+		m.Compute(i, func(n node.Node[int, int]) node.Node[int, int] {
+			return newTestNode(nm, i, i)
+		})
+	}
+	if len(resizes) == 0 {
+		t.Fatal("the table should be grown")
+	}
+	for i, r := range resizes {
+		if r[1] != 2*r[0] {
+			t.Fatalf("the table should be grown by a factor of 2, got: %v", r)
+		}
+		if i == 0 && r[0] != minCapacity {
+			t.Fatalf("the first resize should start from %d, got: %d", minCapacity, r[0])
+		}
+	}
+
+	if capacity := m.Capacity(); capacity != resizes[len(resizes)-1][1] {
+		t.Fatalf("the capacity should be %d, got: %d", resizes[len(resizes)-1][1], capacity)
+	}
+
+	resizes = nil
+	m.Clear()
+	if len(resizes) != 1 || resizes[0][1] != minCapacity {
+		t.Fatalf("the table should be cleared to the capacity of %d, got: %v", minCapacity, resizes)
+	}
+}
+
 func parallelRandTypedResizer(m *Map[string, int, node.Node[string, int]], numIters, numNodes int, cdone chan bool) {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	for i := 0; i < numIters; i++ {
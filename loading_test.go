@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -412,7 +413,9 @@ func TestCache_GetWithSuccessRefresh(t *testing.T) {
 	if snapshot.Hits != 3 ||
 		snapshot.Misses != 0 ||
 		snapshot.Loads() != 1 ||
-		snapshot.LoadSuccesses != 1 {
+		snapshot.LoadSuccesses != 1 ||
+		snapshot.RefreshSuccesses != 1 ||
+		snapshot.StaleServes != 1 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 }
@@ -467,7 +470,9 @@ func TestCache_GetWithNotFoundRefresh(t *testing.T) {
 	if snapshot.Hits != 1 ||
 		snapshot.Misses != 1 ||
 		snapshot.Loads() != 1 ||
-		snapshot.LoadSuccesses != 1 {
+		snapshot.LoadSuccesses != 1 ||
+		snapshot.RefreshSuccesses != 1 ||
+		snapshot.StaleServes != 1 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 }
@@ -546,11 +551,363 @@ func TestCache_Refresh(t *testing.T) {
 	if snapshot.Hits != 2 ||
 		snapshot.Misses != 1 ||
 		snapshot.Loads() != 2 ||
-		snapshot.LoadSuccesses != 2 {
+		snapshot.LoadSuccesses != 2 ||
+		snapshot.RefreshSuccesses != 2 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 }
 
+func TestCache_RefreshStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	statsCounter := stats.NewCounter()
+	// the wrapper hides stats.RefreshRecorder, so the refreshes are recorded only as loads.
+	loadsCounter := stats.NewCounter()
+	newCache := func(recorder stats.Recorder) *Cache[int, int] {
+		return Must(&Options[int, int]{
+			StatsRecorder:     recorder,
+			RefreshCalculator: RefreshWriting[int, int](time.Hour),
+		})
+	}
+	c := newCache(statsCounter)
+	lc := newCache(struct{ stats.Recorder }{loadsCounter})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocking := newTestLoader[int, int](func(ctx context.Context, key int) (int, error) {
+		close(started)
+		<-release
+		return key, nil
+	})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = c.Get(ctx, 1, blocking)
+	}()
+	<-started
+	ch := c.Refresh(ctx, 1, blocking)
+	// the refresh is coalesced with the load in progress.
+	require.Eventually(t, func() bool {
+		return statsCounter.Snapshot().CoalescedLoads == 1
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.NoError(t, (<-ch).Err)
+
+	succeeding := newTestLoader[int, int](func(ctx context.Context, key int) (int, error) {
+		return key, nil
+	})
+	failing := newTestLoader[int, int](func(ctx context.Context, key int) (int, error) {
+		return 0, errors.New("failed")
+	})
+	for _, cache := range []*Cache[int, int]{c, lc} {
+		cache.Set(2, 2)
+		require.NoError(t, (<-cache.Refresh(ctx, 2, succeeding)).Err)
+		require.Error(t, (<-cache.Refresh(ctx, 2, failing)).Err)
+		v, ok := cache.GetIfPresent(2)
+		require.True(t, ok)
+		require.Equal(t, 2, v)
+	}
+
+	// the refreshes are also recorded as loads.
+	snapshot := statsCounter.Snapshot()
+	require.Equal(t, uint64(2), snapshot.LoadSuccesses)
+	require.Equal(t, uint64(1), snapshot.LoadFailures)
+	require.Equal(t, uint64(1), snapshot.RefreshSuccesses)
+	require.Equal(t, uint64(1), snapshot.RefreshFailures)
+	require.Equal(t, uint64(2), snapshot.Refreshes())
+	require.Equal(t, uint64(1), snapshot.CoalescedLoads)
+
+	snapshot = loadsCounter.Snapshot()
+	require.Equal(t, uint64(1), snapshot.LoadSuccesses)
+	require.Equal(t, uint64(1), snapshot.LoadFailures)
+	require.Equal(t, uint64(0), snapshot.Refreshes())
+}
+
+func TestCache_NegativeCaching(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mc := newManualClock()
+	c := Must(&Options[int, int]{
+		NegativeTTL: time.Minute,
+		Clock:       mc,
+	})
+
+	var loads atomic.Int64
+	loader := LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		loads.Add(1)
+		if key < 0 {
+			return 0, ErrNotFound
+		}
+		return key, nil
+	})
+
+	_, state := c.Lookup(-1)
+	require.Equal(t, NotCached, state)
+	_, err := c.Get(ctx, -1, loader)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NotErrorIs(t, err, ErrKnownAbsent)
+	require.Equal(t, int64(1), loads.Load())
+
+	// the absence is remembered.
+	_, err = c.Get(ctx, -1, loader)
+	require.ErrorIs(t, err, ErrKnownAbsent)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, int64(1), loads.Load())
+	_, state = c.Lookup(-1)
+	require.Equal(t, KnownAbsent, state)
+	_, ok := c.GetIfPresent(-1)
+	require.False(t, ok)
+
+	result, err := c.BulkGet(ctx, []int{-1, 1}, BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		require.Equal(t, []int{1}, keys)
+		return map[int]int{1: 1}, nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 1}, result)
+	v, state := c.Lookup(1)
+	require.Equal(t, Present, state)
+	require.Equal(t, 1, v)
+
+	// the absence expires.
+	mc.advance(time.Minute)
+	_, err = c.Get(ctx, -1, loader)
+	require.NotErrorIs(t, err, ErrKnownAbsent)
+	require.Equal(t, int64(2), loads.Load())
+
+	// the absence is forgotten on writes and invalidations.
+	c.Set(-1, 10)
+	c.Invalidate(-1)
+	_, state = c.Lookup(-1)
+	require.Equal(t, NotCached, state)
+
+	_, err = c.Get(ctx, -2, loader)
+	require.ErrorIs(t, err, ErrNotFound)
+	c.Invalidate(-2)
+	_, state = c.Lookup(-2)
+	require.Equal(t, NotCached, state)
+
+	_, _ = c.Get(ctx, -3, loader)
+	require.Equal(t, 1, c.Diagnostics().KnownAbsentSize)
+	c.InvalidateAll()
+	_, state = c.Lookup(-3)
+	require.Equal(t, NotCached, state)
+}
+
+// yieldingClock yields the processor on every read of the time to widen the race windows.
+type yieldingClock struct{}
+
+func (yc *yieldingClock) NowNano() int64 {
+	runtime.Gosched()
+	return time.Now().UnixNano()
+}
+
+func (yc *yieldingClock) Tick(duration time.Duration) <-chan time.Time {
+	return nil
+}
+
+func TestCache_NegativeCachingConcurrentSet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := Must(&Options[int, int]{
+		NegativeTTL: time.Hour,
+		Clock:       &yieldingClock{},
+	})
+	loader := LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		return 0, ErrNotFound
+	})
+
+	for i := 0; i < 1000; i++ {
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, _ = c.Get(ctx, i, loader)
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			c.Set(i, i)
+		}()
+		close(start)
+		wg.Wait()
+
+		// the absence must not outlive a value written concurrently with the load.
+		if _, ok := c.GetIfPresent(i); ok {
+			require.False(t, c.cache.isKnownAbsent(i), "key %d has a value, but is known to be absent", i)
+		}
+	}
+}
+
+func TestCache_NegativeCachingWeight(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := Must(&Options[int, int]{
+		NegativeTTL:           time.Hour,
+		NegativeMaximumWeight: 10,
+		NegativeWeigher: func(key int) uint32 {
+			return 5
+		},
+	})
+	notFound := LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		return 0, ErrNotFound
+	})
+
+	for i := 0; i < 10; i++ {
+		_, err := c.Get(ctx, i, notFound)
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+	c.cache.negative.CleanUp()
+
+	d := c.Diagnostics()
+	require.Equal(t, 2, d.KnownAbsentSize)
+	require.Equal(t, uint64(10), d.KnownAbsentWeightedSize)
+	// the known absences don't count towards the size of the cache.
+	require.Equal(t, 0, c.EstimatedSize())
+}
+
+func TestCache_StaleIfError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mc := newManualClock()
+	c := Must(&Options[int, int]{
+		ExpiryCalculator: ExpiryWriting[int, int](time.Minute),
+		GraceCalculator:  GraceExpiring[int, int](time.Hour),
+		Clock:            mc,
+	})
+
+	loadErr := errors.New("failed")
+	failing := LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		return 0, loadErr
+	})
+
+	// the expired entry is not evicted yet.
+	c.Set(1, 1)
+	mc.advance(2 * time.Minute)
+	v, err := c.Get(ctx, 1, failing)
+	require.ErrorIs(t, err, ErrStale)
+	require.ErrorIs(t, err, loadErr)
+	require.Equal(t, 1, v)
+	_, ok := c.GetIfPresent(1)
+	require.False(t, ok)
+
+	// the expired entry is evicted.
+	c.CleanUp()
+	require.Equal(t, 0, c.EstimatedSize())
+	require.Equal(t, 1, c.Diagnostics().StaleSize)
+	v, err = c.Get(ctx, 1, failing)
+	require.ErrorIs(t, err, ErrStale)
+	require.Equal(t, 1, v)
+
+	result, err := c.BulkGet(ctx, []int{1, 2}, BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		return nil, loadErr
+	}))
+	require.ErrorIs(t, err, ErrStale)
+	require.ErrorIs(t, err, loadErr)
+	require.Equal(t, map[int]int{1: 1}, result)
+
+	// a successful load replaces the stale value.
+	v, err = c.Get(ctx, 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		return 10, nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, 10, v)
+	mc.advance(2 * time.Minute)
+	v, err = c.Get(ctx, 1, failing)
+	require.ErrorIs(t, err, ErrStale)
+	require.Equal(t, 10, v)
+
+	// the grace period ends.
+	c.CleanUp()
+	mc.advance(time.Hour)
+	v, err = c.Get(ctx, 1, failing)
+	require.NotErrorIs(t, err, ErrStale)
+	require.ErrorIs(t, err, loadErr)
+	require.Equal(t, 0, v)
+
+	// the grace period ends when the key is invalidated.
+	c.Set(2, 2)
+	mc.advance(2 * time.Minute)
+	c.CleanUp()
+	c.Invalidate(2)
+	_, err = c.Get(ctx, 2, failing)
+	require.NotErrorIs(t, err, ErrStale)
+
+	// the grace period ends when the entry is not found in the data source.
+	c.Set(3, 3)
+	mc.advance(2 * time.Minute)
+	c.CleanUp()
+	_, err = c.Get(ctx, 3, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		return 0, ErrNotFound
+	}))
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NotErrorIs(t, err, ErrStale)
+	_, err = c.Get(ctx, 3, failing)
+	require.NotErrorIs(t, err, ErrStale)
+
+	c.Set(4, 4)
+	mc.advance(2 * time.Minute)
+	c.CleanUp()
+	c.InvalidateAll()
+	require.Equal(t, 0, c.Diagnostics().StaleSize)
+}
+
+func TestCache_StaleIfErrorGraceCalculator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mc := newManualClock()
+	c := Must(&Options[int, int]{
+		ExpiryCalculator: ExpiryWriting[int, int](time.Minute),
+		GraceCalculator: GraceExpiringFunc(func(entry Entry[int, int]) time.Duration {
+			// only the positive values may be stale.
+			if entry.Value > 0 {
+				return time.Duration(entry.Value) * time.Minute
+			}
+			return 0
+		}),
+		Clock: mc,
+	})
+
+	loadErr := errors.New("failed")
+	failing := LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		return 0, loadErr
+	})
+
+	c.Set(1, 5)
+	c.Set(2, -1)
+	mc.advance(3 * time.Minute)
+	c.CleanUp()
+	require.Equal(t, 1, c.Diagnostics().StaleSize)
+
+	v, err := c.Get(ctx, 1, failing)
+	require.ErrorIs(t, err, ErrStale)
+	require.Equal(t, 5, v)
+	_, err = c.Get(ctx, 2, failing)
+	require.NotErrorIs(t, err, ErrStale)
+
+	mc.advance(3 * time.Minute)
+	_, err = c.Get(ctx, 1, failing)
+	require.NotErrorIs(t, err, ErrStale)
+}
+
+func TestPresence_String(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "NotCached", NotCached.String())
+	require.Equal(t, "Present", Present.String())
+	require.Equal(t, "KnownAbsent", KnownAbsent.String())
+	require.Equal(t, "<unknown otter.Presence>", Presence(-1).String())
+}
+
 func TestCache_BulkGetWithSuccessLoad(t *testing.T) {
 	t.Parallel()
 
@@ -711,7 +1068,9 @@ func TestCache_BulkGetWithSuccessRefresh(t *testing.T) {
 	if snapshot.Hits != 20 ||
 		snapshot.Misses != 0 ||
 		snapshot.Loads() != 1 ||
-		snapshot.LoadSuccesses != 1 {
+		snapshot.LoadSuccesses != 1 ||
+		snapshot.RefreshSuccesses != 1 ||
+		snapshot.StaleServes != 6 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 }
@@ -796,7 +1155,9 @@ func TestCache_BulkGetWithNotFoundRefresh(t *testing.T) {
 	if snapshot.Hits != uint64(2*len(keys)-1) ||
 		snapshot.Misses != 1 ||
 		snapshot.Loads() != 1 ||
-		snapshot.LoadSuccesses != 1 {
+		snapshot.LoadSuccesses != 1 ||
+		snapshot.RefreshSuccesses != 1 ||
+		snapshot.StaleServes != 4 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 
@@ -890,7 +1251,9 @@ func TestCache_BulkGetWithFakeCall(t *testing.T) {
 	if snapshot.Hits != uint64(2*len(keys)+1) ||
 		snapshot.Misses != 1 ||
 		snapshot.Loads() != 1 ||
-		snapshot.LoadSuccesses != 1 {
+		snapshot.LoadSuccesses != 1 ||
+		snapshot.RefreshSuccesses != 1 ||
+		snapshot.StaleServes != 3 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 
@@ -992,7 +1355,8 @@ func TestCache_BulkRefresh(t *testing.T) {
 	if snapshot.Hits != 15 ||
 		snapshot.Misses != 7 ||
 		snapshot.Loads() != 2 ||
-		snapshot.LoadSuccesses != 2 {
+		snapshot.LoadSuccesses != 2 ||
+		snapshot.RefreshSuccesses != 2 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 }
@@ -1105,7 +1469,9 @@ func TestCache_BulkRefreshResults(t *testing.T) {
 	if snapshot.Hits != 3 ||
 		snapshot.Misses != 1 ||
 		snapshot.Loads() != 2 ||
-		snapshot.LoadSuccesses != 2 {
+		snapshot.LoadSuccesses != 2 ||
+		snapshot.RefreshSuccesses != 1 ||
+		snapshot.CoalescedLoads != 1 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 }
@@ -1222,7 +1588,9 @@ func TestCache_GetWithFailedRefresh(t *testing.T) {
 		snapshot.Misses != 0 ||
 		snapshot.Loads() != 2 ||
 		snapshot.LoadSuccesses != 1 ||
-		snapshot.LoadFailures != 1 {
+		snapshot.LoadFailures != 1 ||
+		snapshot.RefreshSuccesses != 1 ||
+		snapshot.RefreshFailures != 1 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 }
@@ -1348,7 +1716,9 @@ func TestCache_BulkGetWithFailedRefresh(t *testing.T) {
 		snapshot.Misses != 0 ||
 		snapshot.Loads() != 1 ||
 		snapshot.LoadSuccesses != 0 ||
-		snapshot.LoadFailures != 1 {
+		snapshot.LoadFailures != 1 ||
+		snapshot.Refreshes() != 1 ||
+		snapshot.RefreshFailures != 1 {
 		t.Fatalf("statistics are not recorded correctly. snapshot: %v", snapshot)
 	}
 }
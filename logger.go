@@ -27,6 +27,54 @@ type Logger interface {
 	Error(ctx context.Context, msg string, err error)
 }
 
+// DebugLogger is an optional interface of [Logger] that is used to get debug events from otter,
+// such as evictions, expirations, resizes of the hash table, adjustments of the eviction policy's window
+// and read buffer events dropped because the buffer was full.
+// These events are frequent, so they are logged only if DebugEnabled returns true.
+type DebugLogger interface {
+	Logger
+	// DebugEnabled reports whether the debug events should be logged.
+	DebugEnabled(ctx context.Context) bool
+	// Debug logs a message at the debug level with attributes.
+	Debug(ctx context.Context, msg string, attrs ...slog.Attr)
+}
+
+// SlogLogger is a [DebugLogger] implementation based on [slog.Logger].
+// The debug events are logged if the logger's handler is enabled for [slog.LevelDebug].
+type SlogLogger struct {
+	log *slog.Logger
+}
+
+// NewSlogLogger returns a new [SlogLogger] that uses log. If log is nil, slog.Default() is used.
+func NewSlogLogger(log *slog.Logger) *SlogLogger {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &SlogLogger{
+		log: log,
+	}
+}
+
+// Warn logs a message at the warn level with an error.
+func (sl *SlogLogger) Warn(ctx context.Context, msg string, err error) {
+	sl.log.WarnContext(ctx, msg, slog.Any("err", err))
+}
+
+// Error logs a message at the error level with an error.
+func (sl *SlogLogger) Error(ctx context.Context, msg string, err error) {
+	sl.log.ErrorContext(ctx, msg, slog.Any("err", err))
+}
+
+// DebugEnabled reports whether the logger's handler is enabled for [slog.LevelDebug].
+func (sl *SlogLogger) DebugEnabled(ctx context.Context) bool {
+	return sl.log.Enabled(ctx, slog.LevelDebug)
+}
+
+// Debug logs a message at the debug level with attributes.
+func (sl *SlogLogger) Debug(ctx context.Context, msg string, attrs ...slog.Attr) {
+	sl.log.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
 type defaultLogger struct {
 	log *slog.Logger
 }
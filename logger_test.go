@@ -22,7 +22,9 @@ import (
 	"log/slog"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -88,3 +90,88 @@ func TestNoopLogger(t *testing.T) {
 		nl.Error(context.Background(), "ytuvut", errors.New("hjihiuh"))
 	})
 }
+
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.buf.String()
+}
+
+func TestSlogLogger(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	removeTime := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return a
+	}
+	ctx := context.Background()
+
+	sl := NewSlogLogger(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{ReplaceAttr: removeTime})))
+	require.False(t, sl.DebugEnabled(ctx))
+	sl.Error(ctx, "lololol", ErrNotFound)
+	sl.Warn(ctx, "qokpokp", errors.New("gol"))
+	require.Equal(t,
+		"level=ERROR msg=lololol err=\"otter: the entry was not found in the data source\"\n"+
+			"level=WARN msg=qokpokp err=gol\n",
+		b.String())
+	b.Reset()
+
+	sl = NewSlogLogger(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{
+		Level:       slog.LevelDebug,
+		ReplaceAttr: removeTime,
+	})))
+	require.True(t, sl.DebugEnabled(ctx))
+	sl.Debug(ctx, "hfhfhf", slog.Int("key", 1))
+	require.Equal(t, "level=DEBUG msg=hfhfhf key=1\n", b.String())
+
+	require.NotNil(t, NewSlogLogger(nil).log)
+}
+
+func TestCache_DebugEvents(t *testing.T) {
+	t.Parallel()
+
+	var b syncBuffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	})))
+
+	unbounded := Must(&Options[int, int]{
+		Logger: logger,
+	})
+	for i := 0; i < 1000; i++ {
+		unbounded.Set(i, i)
+	}
+	require.Contains(t, b.String(), "msg=\"Resized the hash table\"")
+
+	mc := newManualClock()
+	c := Must(&Options[int, int]{
+		MaximumSize:      10,
+		ExpiryCalculator: ExpiryWriting[int, int](time.Minute),
+		Clock:            mc,
+		Logger:           logger,
+	})
+	for i := 0; i < 100; i++ {
+		c.Set(i, i)
+	}
+	c.CleanUp()
+	require.Contains(t, b.String(), "msg=\"Evicted an entry\" key=0 cause=Overflow weight=1")
+
+	mc.advance(time.Hour)
+	c.CleanUp()
+	require.Contains(t, b.String(), "cause=Expiration weight=1")
+	require.Equal(t, 0, c.EstimatedSize())
+}
@@ -16,6 +16,7 @@ package otter
 
 import (
 	"errors"
+	"time"
 
 	"github.com/maypok86/otter/v2/stats"
 )
@@ -87,6 +88,12 @@ type Options[K comparable, V any] struct {
 	// NOTE: If your stats.Recorder implementation doesn't also implement stats.Snapshoter,
 	// Cache.Stats method will always return a zero-value snapshot.
 	StatsRecorder stats.Recorder
+	// StatsClassifier maps a key to the name of its group (for example, a tenant or an endpoint), so that
+	// the hits, misses, loads and deletions are also recorded per group. It requires StatsRecorder to
+	// implement stats.GroupRecorder (for example, stats.GroupedCounter).
+	//
+	// The number of distinct groups should be small, since the statistics are kept for every group.
+	StatsClassifier func(key K) string
 	// InitialCapacity specifies the minimum total size for the internal data structures. Providing a large enough estimate
 	// at construction time avoids the need for expensive resizing operations later, but setting this
 	// value unnecessarily high wastes memory.
@@ -104,6 +111,32 @@ type Options[K comparable, V any] struct {
 	// elapsed after the entry's creation, the most recent replacement of its value, or its last read.
 	// The expiration time is reset by all cache read and write operations.
 	ExpiryCalculator ExpiryCalculator[K, V]
+	// GraceCalculator specifies that the expired entries should be retained for a grace period, so that
+	// Cache.Get and Cache.BulkGet can return them along with ErrStale when the loader fails to load
+	// their new values. An entry that is within its grace period is not visible to the other methods of Cache,
+	// and its grace period ends early when the key is invalidated or the loader reports ErrNotFound for it.
+	// Use of this option requires specifying ExpiryCalculator.
+	//
+	// The expired entries are kept separately from the entries, so they don't count towards the size of the cache,
+	// but their number (or weight) is limited by MaximumSize (or MaximumWeight).
+	GraceCalculator GraceCalculator[K, V]
+	// NegativeTTL enables the caching of ErrNotFound results. When a Loader or BulkLoader reports that a key
+	// was not found in the data source, the cache remembers the absence of the key for NegativeTTL, so
+	// Cache.Get returns ErrKnownAbsent and Cache.BulkGet skips the key without calling the loader.
+	// The absence of a key is forgotten when a value is written for the key or the key is invalidated.
+	//
+	// The known absences are kept separately from the entries, so they don't count towards
+	// MaximumSize or MaximumWeight. By default, ErrNotFound results are not cached.
+	NegativeTTL time.Duration
+	// NegativeMaximumWeight specifies the maximum weight of the known absences the cache may contain.
+	// Weight is determined using NegativeWeigher, or is 1 for every key if NegativeWeigher is not specified.
+	// Use of this option requires specifying NegativeTTL.
+	//
+	// By default, the number of known absences is limited only by NegativeTTL.
+	NegativeMaximumWeight uint64
+	// NegativeWeigher specifies the weigher to use in determining the weight of the known absences.
+	// Use of this option requires specifying NegativeMaximumWeight.
+	NegativeWeigher func(key K) uint32
 	// OnDeletion specifies a handler instance that caches should notify each time an entry is deleted for any
 	// DeletionCause reason. The cache will invoke this handler on the configured Executor
 	// after the entry's deletion operation has completed.
@@ -128,6 +161,30 @@ type Options[K comparable, V any] struct {
 	//
 	// NOTE: all errors returned during refresh will be logged (using Logger) and then swallowed.
 	RefreshCalculator RefreshCalculator[K, V]
+	// RetryPolicy specifies how the failed calls of Loader and BulkLoader are retried. It applies
+	// uniformly to the loads of Cache.Get and Cache.BulkGet and to the refreshes. The retries happen
+	// within the same load, so the goroutines waiting for the key keep waiting until the last attempt.
+	//
+	// By default, the failed loads are not retried.
+	RetryPolicy *RetryPolicy
+	// LoadTimeout specifies the maximum duration of the loads made by Cache.Get and Cache.BulkGet,
+	// including their retries. The context passed to the loader is canceled after LoadTimeout,
+	// and the caller and the goroutines waiting for the same keys get ErrLoadTimeout
+	// even if the loader ignores its context.
+	//
+	// NOTE: a loader that ignores its context keeps running in a separate goroutine after the timeout
+	// and its result is discarded. The loads are run in separate goroutines only if LoadTimeout is specified.
+	//
+	// By default, the loads are bounded only by the context of the first caller.
+	LoadTimeout time.Duration
+	// RefreshTimeout is like LoadTimeout, but bounds the reloads made by Cache.Refresh, Cache.BulkRefresh
+	// and the background refreshes, which otherwise run without the cancellation of the caller's context.
+	//
+	// NOTE: the cache refreshes entries only if RefreshCalculator is specified, so without it
+	// RefreshTimeout is accepted but has no effect.
+	//
+	// By default, the refreshes are not bounded.
+	RefreshTimeout time.Duration
 	// Executor specifies the executor to use when running asynchronous tasks. The executor is delegated to
 	// when sending deletion events, when asynchronous computations are performed by
 	// Cache.Refresh/Cache.BulkRefresh or for refreshes in Cache.Get/Cache.BulkGet, if RefreshCalculator was specified,
@@ -144,14 +201,22 @@ type Options[K comparable, V any] struct {
 	// expired or refreshed. By default, time.Now().UnixNano() is used.
 	//
 	// The primary intent of this option is to facilitate testing of caches which have been configured
-	// with ExpiryCalculator or RefreshCalculator.
+	// with ExpiryCalculator or RefreshCalculator. If the clock implements Sleeper, it's also used to wait
+	// between the retries of failed loads (see RetryPolicy).
 	//
 	// NOTE: this clock is not used when recording statistics.
 	Clock Clock
 	// Logger specifies the Logger implementation that will be used for logging warning and errors.
+	// If the Logger also implements DebugLogger (for example, SlogLogger), the cache will log debug events
+	// such as evictions, expirations and resizes of the hash table.
 	//
 	// The cache will use slog.Default() by default.
 	Logger Logger
+	// Tracer specifies the Tracer implementation that will be notified about the start and end of
+	// the loads, refreshes and bulk loads performed by the cache.
+	//
+	// By default, the loads are not traced.
+	Tracer Tracer
 }
 
 func (o *Options[K, V]) getMaximum() uint64 {
@@ -198,6 +263,61 @@ func (o *Options[K, V]) getLogger() Logger {
 	return o.Logger
 }
 
+// getNegativeOptions returns the options of the cache that keeps the known absences.
+func (o *Options[K, V]) getNegativeOptions() *Options[K, struct{}] {
+	no := &Options[K, struct{}]{
+		ExpiryCalculator: ExpiryWriting[K, struct{}](o.NegativeTTL),
+		Executor:         o.Executor,
+		Clock:            o.Clock,
+		Logger:           o.Logger,
+	}
+	if o.NegativeMaximumWeight > 0 {
+		no.MaximumWeight = o.NegativeMaximumWeight
+		if weigher := o.NegativeWeigher; weigher != nil {
+			no.Weigher = func(key K, _ struct{}) uint32 {
+				return weigher(key)
+			}
+		} else {
+			no.Weigher = func(key K, _ struct{}) uint32 {
+				return 1
+			}
+		}
+	}
+	return no
+}
+
+// getStaleOptions returns the options of the cache that keeps the expired entries within their grace period.
+//
+// The values are stored as any, since a cache of staleValue[V] would instantiate itself recursively.
+func (o *Options[K, V]) getStaleOptions() *Options[K, any] {
+	so := &Options[K, any]{
+		MaximumSize: o.MaximumSize,
+		ExpiryCalculator: ExpiryCreatingFunc(func(entry Entry[K, any]) time.Duration {
+			//nolint:errcheck // the stale cache contains only staleValue[V]
+			return time.Duration(entry.Value.(staleValue[V]).deadline - entry.SnapshotAtNano)
+		}),
+		Executor: o.Executor,
+		Clock:    o.Clock,
+		Logger:   o.Logger,
+	}
+	if o.MaximumWeight > 0 {
+		so.MaximumWeight = o.MaximumWeight
+		weigher := o.Weigher
+		so.Weigher = func(key K, value any) uint32 {
+			//nolint:errcheck // the stale cache contains only staleValue[V]
+			return weigher(key, value.(staleValue[V]).value)
+		}
+	}
+	return so
+}
+
+func (o *Options[K, V]) getTracer() Tracer {
+	if _, ok := o.Tracer.(*NoopTracer); ok {
+		return nil
+	}
+	return o.Tracer
+}
+
 func (o *Options[K, V]) validate() error {
 	if o.MaximumSize > 0 && o.MaximumWeight > 0 {
 		return errors.New("otter: both maximumSize and maximumWeight are set")
@@ -213,9 +333,39 @@ func (o *Options[K, V]) validate() error {
 		return errors.New("otter: weigher requires maximumWeight")
 	}
 
+	if o.StatsClassifier != nil {
+		if _, ok := o.StatsRecorder.(stats.GroupRecorder); !ok {
+			return errors.New("otter: statsClassifier requires stats.GroupRecorder")
+		}
+	}
+
+	if o.GraceCalculator != nil && o.ExpiryCalculator == nil {
+		return errors.New("otter: graceCalculator requires expiryCalculator")
+	}
+
+	if o.NegativeMaximumWeight > 0 && o.NegativeTTL <= 0 {
+		return errors.New("otter: negativeMaximumWeight requires negativeTTL")
+	}
+	if o.NegativeWeigher != nil && o.NegativeMaximumWeight <= 0 {
+		return errors.New("otter: negativeWeigher requires negativeMaximumWeight")
+	}
+
+	if p := o.RetryPolicy; p != nil && (p.Jitter < 0 || p.Jitter > 1) {
+		return errors.New("otter: retryPolicy jitter should be between 0 and 1")
+	}
+
 	if o.MaximumSize < 0 {
 		return errors.New("otter: maximumSize should be positive")
 	}
+	if o.LoadTimeout < 0 {
+		return errors.New("otter: loadTimeout should be positive")
+	}
+	if o.RefreshTimeout < 0 {
+		return errors.New("otter: refreshTimeout should be positive")
+	}
+	if o.NegativeTTL < 0 {
+		return errors.New("otter: negativeTTL should be positive")
+	}
 	if o.InitialCapacity < 0 {
 		return errors.New("otter: initial capacity should be positive")
 	}
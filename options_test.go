@@ -74,6 +74,63 @@ func TestOptions(t *testing.T) {
 			},
 			want: ptr("otter: initial capacity should be positive"),
 		},
+		{
+			fn: func(o *Options[string, string]) {
+				o.StatsRecorder = stats.NewCounter()
+				o.StatsClassifier = func(key string) string {
+					return key
+				}
+			},
+			want: ptr("otter: statsClassifier requires stats.GroupRecorder"),
+		},
+		{
+			fn: func(o *Options[string, string]) {
+				o.LoadTimeout = -1
+			},
+			want: ptr("otter: loadTimeout should be positive"),
+		},
+		{
+			fn: func(o *Options[string, string]) {
+				o.RefreshTimeout = -1
+			},
+			want: ptr("otter: refreshTimeout should be positive"),
+		},
+		{
+			fn: func(o *Options[string, string]) {
+				o.RetryPolicy = &RetryPolicy{
+					MaxAttempts: 3,
+					Jitter:      1.5,
+				}
+			},
+			want: ptr("otter: retryPolicy jitter should be between 0 and 1"),
+		},
+		{
+			fn: func(o *Options[string, string]) {
+				o.GraceCalculator = GraceExpiring[string, string](time.Minute)
+			},
+			want: ptr("otter: graceCalculator requires expiryCalculator"),
+		},
+		{
+			fn: func(o *Options[string, string]) {
+				o.NegativeTTL = -1
+			},
+			want: ptr("otter: negativeTTL should be positive"),
+		},
+		{
+			fn: func(o *Options[string, string]) {
+				o.NegativeMaximumWeight = 10
+			},
+			want: ptr("otter: negativeMaximumWeight requires negativeTTL"),
+		},
+		{
+			fn: func(o *Options[string, string]) {
+				o.NegativeTTL = time.Minute
+				o.NegativeWeigher = func(key string) uint32 {
+					return 1
+				}
+			},
+			want: ptr("otter: negativeWeigher requires negativeMaximumWeight"),
+		},
 		{
 			fn: func(o *Options[string, string]) {
 				o.MaximumWeight = 10
@@ -21,13 +21,184 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/maypok86/otter/v2/internal/xmath"
+)
+
+// SaveOptions configures how [SaveCacheTo] and [SaveCacheToFile] persist cache data.
+//
+// The zero value is ready to use.
+type SaveOptions[K comparable, V any] struct {
+	// Codec specifies the codec used to encode cache entries.
+	//
+	// By default, GobCodec is used.
+	Codec Codec[K, V]
+	// Generations specifies the number of previous snapshots that SaveCacheToFile keeps next to the
+	// current one. The previous snapshots are named filePath.1 (the newest) to filePath.N (the oldest).
+	//
+	// By default, previous snapshots are not kept.
+	Generations int
+	// Compressor specifies the compression of the snapshot data.
+	//
+	// By default, the data is not compressed.
+	Compressor Compressor
+	// PreservePolicy specifies that the snapshot should also contain the state of the eviction policy:
+	// the estimated frequency and the queue (window, probation or protected) of every entry.
+	// LoadCacheFrom uses this state to reproduce the eviction order and the hit ratio of the saved cache.
+	//
+	// By default, only the order of the entries is saved, and LoadCacheFrom approximates their frequencies.
+	// PreservePolicy is ignored for caches without a maximum size or weight.
+	PreservePolicy bool
+	// Shards specifies the number of shards the snapshot is split into. The shards are encoded and compressed
+	// independently, which allows LoadCacheFrom to decode and insert them concurrently. The number of shards
+	// is determined using the estimated size of the cache, so it may differ slightly from the specified one.
+	//
+	// NOTE: LoadCacheFrom reads a sharded snapshot into memory and decodes all its entries before inserting them.
+	//
+	// By default, the snapshot is not sharded.
+	Shards int
+	// Filter reports whether the entry should be saved.
+	//
+	// By default, all entries are saved.
+	Filter func(entry Entry[K, V]) bool
+	// Transform is called for every entry that passes the Filter and returns the entry to save instead of it.
+	// It may rewrite the key, the value, the weight and the expiration and refresh times of the entry.
+	// If Transform returns false, the entry is not saved.
+	//
+	// The returned weight is used to limit the saved entries by the maximum of the cache.
+	Transform func(entry Entry[K, V]) (Entry[K, V], bool)
+}
+
+func (o *SaveOptions[K, V]) getCodec() Codec[K, V] {
+	if o.Codec == nil {
+		return GobCodec[K, V]{}
+	}
+	return o.Codec
+}
+
+// LoadOptions configures how [LoadCacheFrom] and [LoadCacheFromFile] restore cache data.
+//
+// The zero value is ready to use.
+type LoadOptions[K comparable, V any] struct {
+	// Codec specifies the codec used to decode cache entries. It must be
+	// compatible with the codec that was used to save the data.
+	//
+	// By default, GobCodec is used.
+	Codec Codec[K, V]
+	// Compressors specifies additional compressors that may be used to decompress the snapshot data.
+	// The compression is detected automatically from the snapshot header, and GzipCompressor,
+	// ZlibCompressor and FlateCompressor are always available.
+	Compressors []Compressor
+	// Parallelism specifies the maximum number of goroutines that decode and insert the shards
+	// of a sharded snapshot. Snapshots that are not sharded are always loaded by a single goroutine.
+	//
+	// By default, runtime.GOMAXPROCS(0) is used.
+	Parallelism int
+	// Filter reports whether the saved entry should be loaded.
+	//
+	// By default, all unexpired entries are loaded.
+	Filter func(entry Entry[K, V]) bool
+	// Transform is called for every saved entry that passes the Filter and returns the entry to load instead of it,
+	// which allows to migrate the saved values to a new schema. It may rewrite the key, the value, the weight
+	// and the expiration and refresh times of the entry. If Transform returns false, the entry is not loaded.
+	//
+	// The returned weight is used to select the entries that fit into the maximum of the cache,
+	// while the weight of the loaded entry is still determined by the cache's Weigher.
+	// Filter and Transform are never called concurrently.
+	Transform func(entry Entry[K, V]) (Entry[K, V], bool)
+	// ExpiryPolicy specifies how the expiration and refresh times of the loaded entries are determined.
+	//
+	// By default, LoadExpiryKeepDeadline is used.
+	ExpiryPolicy LoadExpiryPolicy
+	// Report, if not nil, is filled with the statistics of a successful load.
+	Report *LoadReport
+}
+
+// LoadExpiryPolicy specifies how the expiration and refresh times of the loaded entries are determined.
+type LoadExpiryPolicy int
+
+const (
+	// LoadExpiryKeepDeadline keeps the saved expiration and refresh times (deadlines) of the entries.
+	// The entries whose deadline has passed are not loaded.
+	//
+	// This policy is sensitive to the clock difference between the saving and the loading hosts.
+	LoadExpiryKeepDeadline LoadExpiryPolicy = iota
+	// LoadExpiryKeepRemaining keeps the durations that remained until the expiration and refresh
+	// of the entries at the time of saving, so the time between saving and loading is not counted.
+	LoadExpiryKeepRemaining
+	// LoadExpiryRecompute ignores the saved expiration and refresh times and computes them using
+	// the cache's ExpiryCalculator.ExpireAfterCreate and RefreshCalculator.RefreshAfterCreate
+	// as if the entries were just inserted.
+	LoadExpiryRecompute
 )
 
+// LoadReport contains the statistics of loading a snapshot.
+//
+// Loaded + Expired + Skipped + Truncated is always equal to Saved.
+type LoadReport struct {
+	// Saved is the number of entries in the snapshot.
+	Saved int
+	// Loaded is the number of entries inserted into the cache.
+	Loaded int
+	// Expired is the number of entries that had already expired.
+	Expired int
+	// Skipped is the number of entries dropped by LoadOptions.Filter or LoadOptions.Transform.
+	Skipped int
+	// Truncated is the number of entries that didn't fit into the maximum of the cache.
+	Truncated int
+}
+
+func (o *LoadOptions[K, V]) getCodec() Codec[K, V] {
+	if o.Codec == nil {
+		return GobCodec[K, V]{}
+	}
+	return o.Codec
+}
+
+func (o *LoadOptions[K, V]) getParallelism() int {
+	if o.Parallelism <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.Parallelism
+}
+
+// transformEntry applies the filter and the transform (any of which may be nil) to the entry.
+// It returns false if the entry should be dropped.
+func transformEntry[K comparable, V any](
+	entry Entry[K, V],
+	filter func(entry Entry[K, V]) bool,
+	transform func(entry Entry[K, V]) (Entry[K, V], bool),
+) (Entry[K, V], bool) {
+	if filter != nil && !filter(entry) {
+		return entry, false
+	}
+	if transform != nil {
+		return transform(entry)
+	}
+	return entry, true
+}
+
+// lastOptions returns the last non-nil options or the zero value if there are none.
+func lastOptions[O any](opts []*O) *O {
+	for i := len(opts) - 1; i >= 0; i-- {
+		if opts[i] != nil {
+			return opts[i]
+		}
+	}
+	return new(O)
+}
+
 // LoadCacheFromFile loads cache data from the given filePath.
 //
 // See SaveCacheToFile for saving cache data to file.
-func LoadCacheFromFile[K comparable, V any](c *Cache[K, V], filePath string) error {
+func LoadCacheFromFile[K comparable, V any](c *Cache[K, V], filePath string, opts ...*LoadOptions[K, V]) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("otter: open file %s: %w", filePath, err)
@@ -35,72 +206,422 @@ func LoadCacheFromFile[K comparable, V any](c *Cache[K, V], filePath string) err
 	//nolint:errcheck // it's ok
 	defer file.Close()
 
-	return LoadCacheFrom(c, file)
+	return LoadCacheFrom(c, file, opts...)
 }
 
 // LoadCacheFrom loads cache data from the given [io.Reader].
 //
+// The snapshot is fully read and verified before any entry is inserted into the cache, so LoadCacheFrom
+// returns an error and leaves the cache untouched if the snapshot is corrupted ([ErrCorruptedSnapshot]),
+// was not produced by SaveCacheTo ([ErrInvalidSnapshot], [ErrUnsupportedSnapshotVersion]) or
+// was saved from a cache with different key or value types ([ErrSnapshotTypeMismatch]).
+// Entries that the Codec fails to decode are also reported as [ErrCorruptedSnapshot].
+//
+// Since nothing is inserted until the snapshot is verified, LoadCacheFrom keeps the entries selected for loading
+// in memory. A sharded snapshot is additionally read into memory as a whole, and all its entries are decoded
+// before the selection, so loading it temporarily takes memory proportional to the size of the snapshot
+// and the number of the saved entries.
+//
+// Snapshots saved by otter before the versioned format was introduced (a gob stream without a header)
+// are still loaded. They are always decoded with [encoding/gob] regardless of the Codec, and since they have
+// no checksum, only truncated or undecodable data is detected ([ErrCorruptedSnapshot]).
+//
+// The compression of the snapshot is detected automatically from its header.
+//
+// If several LoadOptions are specified, only the last non-nil one is used.
+//
 // See SaveCacheToFile for saving cache data to file.
-func LoadCacheFrom[K comparable, V any](c *Cache[K, V], r io.Reader) error {
-	dec := gob.NewDecoder(r)
+func LoadCacheFrom[K comparable, V any](c *Cache[K, V], r io.Reader, opts ...*LoadOptions[K, V]) error {
+	o := lastOptions(opts)
+	// the clock is needed to skip the expired entries even if the cache doesn't use time.
+	c.cache.clock.Init()
+
+	sr := newSnapshotReader(r)
+	if sr.isLegacy() {
+		return loadLegacySnapshot(c, o, sr.r)
+	}
+	header, err := sr.readHeader()
+	if err != nil {
+		return err
+	}
+	if header.fingerprint != typeFingerprint[K, V]() {
+		return fmt.Errorf("%w: expected %s and %s", ErrSnapshotTypeMismatch, reflect.TypeFor[K](), reflect.TypeFor[V]())
+	}
+	var compressor Compressor
+	if header.compression != "" {
+		var ok bool
+		compressor, ok = findCompressor(header.compression, o.Compressors)
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownSnapshotCompression, header.compression)
+		}
+	}
+	sharded := header.flags&snapshotFlagSharded != 0
+	if compressor != nil && !sharded {
+		if err := sr.decompress(compressor); err != nil {
+			return err
+		}
+		//nolint:errcheck // the data is already verified
+		defer sr.close()
+	}
+
+	var ps *policyState
+	if header.flags&snapshotFlagPolicy != 0 {
+		ps = &policyState{}
+	}
+	verify := func(count uint64) error {
+		return sr.readTrailer(count, ps)
+	}
+	maximum := min(header.maximum, c.GetMaximum())
+	parallelism := 1
+	var sel *entrySelector[K, V]
+	if sharded {
+		parallelism = o.getParallelism()
+		sel, err = readShardedEntries(c, o, sr, compressor, o.getCodec(), maximum, parallelism, verify)
+	} else {
+		sel, err = readEntries(c, o, o.getCodec().NewDecoder(sr), maximum, verify)
+	}
+	if err != nil {
+		return err
+	}
 
+	report := sel.report
+	if ps == nil || !c.cache.withEviction {
+		report.Loaded = loadEntries(c, sel.entries, maximum, parallelism)
+	} else {
+		loaded := make([]bool, len(sel.entries))
+		parallelize(len(sel.entries), parallelism, func(i int) {
+			loaded[i] = loadEntry(c, sel.entries[i])
+		})
+		keys := make([]K, 0, len(sel.entries))
+		states := make([]nodeState, 0, len(sel.entries))
+		for i, entry := range sel.entries {
+			if loaded[i] {
+				keys = append(keys, entry.Key)
+				states = append(states, ps.nodes[sel.positions[i]])
+			}
+		}
+		c.cache.restorePolicy(header.maximum, *ps, keys, states)
+		report.Loaded = len(keys)
+	}
+
+	if o.Report != nil {
+		// the entries may expire between the selection and the insertion.
+		report.Expired += len(sel.entries) - report.Loaded
+		*o.Report = report
+	}
+	return nil
+}
+
+// loadLegacySnapshot loads a snapshot saved by otter before the versioned format was introduced.
+// Such a snapshot has no header and checksum and consists of the gob-encoded maximum
+// followed by the gob-encoded entries.
+func loadLegacySnapshot[K comparable, V any](c *Cache[K, V], o *LoadOptions[K, V], r io.Reader) error {
+	dec := gob.NewDecoder(r)
 	var savedMaximum uint64
 	if err := dec.Decode(&savedMaximum); err != nil {
-		return fmt.Errorf("otter: decode maximum: %w", err)
+		return fmt.Errorf("%w: decode legacy maximum: %w", ErrInvalidSnapshot, err)
 	}
 
 	maximum := min(savedMaximum, c.GetMaximum())
-	maximum2 := maximum / 4
-	maximum1 := 2 * maximum2
-	size := uint64(0)
-	for size < maximum {
+	sel, err := readEntries(c, o, &gobDecoder[K, V]{dec: dec}, maximum, func(uint64) error {
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	report := sel.report
+	report.Loaded = loadEntries(c, sel.entries, maximum, 1)
+	if o.Report != nil {
+		report.Expired += len(sel.entries) - report.Loaded
+		*o.Report = report
+	}
+	return nil
+}
+
+// entrySelector selects the unexpired entries of the snapshot until their total weight reaches the maximum
+// and remembers their positions in the snapshot.
+type entrySelector[K comparable, V any] struct {
+	cache     *Cache[K, V]
+	opts      *LoadOptions[K, V]
+	maximum   uint64
+	entries   []Entry[K, V]
+	positions []uint64
+	count     uint64
+	size      uint64
+	report    LoadReport
+}
+
+func newEntrySelector[K comparable, V any](c *Cache[K, V], opts *LoadOptions[K, V], maximum uint64) *entrySelector[K, V] {
+	return &entrySelector[K, V]{
+		cache:   c,
+		opts:    opts,
+		maximum: maximum,
+	}
+}
+
+// add adds the next entry of the snapshot.
+func (s *entrySelector[K, V]) add(entry Entry[K, V]) {
+	s.count++
+	s.report.Saved++
+
+	if s.size >= s.maximum {
+		s.report.Truncated++
+		return
+	}
+	entry, ok := transformEntry(entry, s.opts.Filter, s.opts.Transform)
+	if !ok {
+		s.report.Skipped++
+		return
+	}
+	nowNano := s.cache.cache.clock.NowNano()
+	entry = applyExpiryPolicy(entry, s.opts.ExpiryPolicy, nowNano)
+	if entry.ExpiresAtNano < nowNano {
+		s.report.Expired++
+		return
+	}
+	s.entries = append(s.entries, entry)
+	s.positions = append(s.positions, s.count-1)
+	s.size += uint64(entry.Weight)
+}
+
+// applyExpiryPolicy converts the saved expiration and refresh times of the entry to the deadlines
+// according to the policy.
+func applyExpiryPolicy[K comparable, V any](entry Entry[K, V], policy LoadExpiryPolicy, nowNano int64) Entry[K, V] {
+	switch policy {
+	case LoadExpiryKeepRemaining:
+		if entry.ExpiresAtNano != unreachableExpiresAt {
+			entry.ExpiresAtNano = xmath.SaturatedAdd(nowNano, int64(entry.ExpiresAfter()))
+		}
+		if entry.RefreshableAtNano != unreachableRefreshableAt {
+			entry.RefreshableAtNano = xmath.SaturatedAdd(nowNano, int64(entry.RefreshableAfter()))
+		}
+		entry.SnapshotAtNano = nowNano
+	case LoadExpiryRecompute:
+		entry.ExpiresAtNano = unreachableExpiresAt
+		entry.RefreshableAtNano = unreachableRefreshableAt
+		entry.SnapshotAtNano = nowNano
+	}
+	return entry
+}
+
+// readEntries decodes all entries of the snapshot and selects the ones to load.
+// The rest of the entries are decoded only to verify the snapshot with the verify function.
+func readEntries[K comparable, V any](
+	c *Cache[K, V],
+	opts *LoadOptions[K, V],
+	dec EntryDecoder[K, V],
+	maximum uint64,
+	verify func(count uint64) error,
+) (*entrySelector[K, V], error) {
+	sel := newEntrySelector(c, opts, maximum)
+	for {
 		var entry Entry[K, V]
-		if err := dec.Decode(&entry); err != nil {
+		if err := decodeEntry(dec, &entry); err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-
-			return fmt.Errorf("otter: decode entry: %w", err)
+			return nil, err
 		}
+		sel.add(entry)
+	}
 
-		nowNano := c.cache.clock.NowNano()
-		if c.cache.withExpiration && entry.ExpiresAtNano < nowNano {
-			continue
+	if err := verify(sel.count); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+// readShardedEntries reads and verifies the shards of the snapshot, decodes them concurrently
+// and selects the entries to load.
+func readShardedEntries[K comparable, V any](
+	c *Cache[K, V],
+	opts *LoadOptions[K, V],
+	sr *snapshotReader,
+	compressor Compressor,
+	codec Codec[K, V],
+	maximum uint64,
+	parallelism int,
+	verify func(count uint64) error,
+) (*entrySelector[K, V], error) {
+	shards, err := sr.readShards()
+	if err != nil {
+		return nil, err
+	}
+	count := uint64(0)
+	for _, shard := range shards {
+		count += shard.count
+	}
+	if err := verify(count); err != nil {
+		return nil, err
+	}
+
+	decoded := make([][]Entry[K, V], len(shards))
+	errs := make([]error, len(shards))
+	parallelize(len(shards), parallelism, func(i int) {
+		decoded[i], errs[i] = decodeShard(shards[i], compressor, codec)
+		// the raw data of the shard is not needed anymore.
+		shards[i].data = nil
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
 		}
-		c.Set(entry.Key, entry.Value)
-		if c.cache.withExpiration && entry.ExpiresAtNano != unreachableExpiresAt {
-			expiresAfter := max(1, time.Duration(entry.ExpiresAtNano-nowNano))
-			c.SetExpiresAfter(entry.Key, expiresAfter)
+	}
+
+	sel := newEntrySelector(c, opts, maximum)
+	for _, entries := range decoded {
+		for _, entry := range entries {
+			sel.add(entry)
 		}
-		if c.cache.withRefresh && entry.RefreshableAtNano != unreachableRefreshableAt {
-			refreshableAfter := max(1, time.Duration(entry.RefreshableAtNano-nowNano))
-			c.SetRefreshableAfter(entry.Key, refreshableAfter)
+	}
+	return sel, nil
+}
+
+func decodeShard[K comparable, V any](shard snapshotShard, compressor Compressor, codec Codec[K, V]) ([]Entry[K, V], error) {
+	sr, err := shard.reader(compressor)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck // the data is already verified
+	defer sr.close()
+
+	entries := make([]Entry[K, V], 0, min(shard.count, 1024))
+	dec := codec.NewDecoder(sr)
+	for {
+		var entry Entry[K, V]
+		if err := decodeEntry(dec, &entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
 		}
-		size += uint64(entry.Weight)
+		entries = append(entries, entry)
+	}
+	if uint64(len(entries)) != shard.count {
+		return nil, fmt.Errorf("%w: expected %d entries in shard, but got %d", ErrCorruptedSnapshot, shard.count, len(entries))
+	}
+	return entries, nil
+}
 
-		if size <= maximum2 {
-			c.GetIfPresent(entry.Key)
-			c.GetIfPresent(entry.Key)
-			continue
+func decodeEntry[K comparable, V any](dec EntryDecoder[K, V], entry *Entry[K, V]) error {
+	if err := dec.Decode(entry); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, ErrCorruptedSnapshot) {
+			return err
 		}
-		if size <= maximum1 {
-			c.GetIfPresent(entry.Key)
-			continue
+		// the checksum is verified after decoding, so a decoding error is most likely caused by corrupted data.
+		return fmt.Errorf("%w: decode entry: %w", ErrCorruptedSnapshot, err)
+	}
+	return nil
+}
+
+// parallelize calls fn for every i in [0, n) using at most parallelism goroutines.
+func parallelize(n, parallelism int, fn func(i int)) {
+	if parallelism <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
 		}
+		return
 	}
 
-	return nil
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+	)
+	for g := 0; g < min(n, parallelism); g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// loadEntries inserts the entries ordered from the hottest to the coldest into the cache
+// and approximates their frequencies.
+//
+// If parallelism is greater than one, contiguous chunks of the entries are inserted concurrently.
+//
+// loadEntries returns the number of the inserted entries.
+func loadEntries[K comparable, V any](c *Cache[K, V], entries []Entry[K, V], maximum uint64, parallelism int) int {
+	maximum2 := maximum / 4
+	maximum1 := 2 * maximum2
+	// the total weights are computed in advance, so that the chunks are independent.
+	sizes := make([]uint64, len(entries))
+	size := uint64(0)
+	for i, entry := range entries {
+		size += uint64(entry.Weight)
+		sizes[i] = size
+	}
+
+	var loaded atomic.Int64
+	chunks := min(len(entries), max(1, parallelism))
+	parallelize(chunks, chunks, func(chunk int) {
+		from := chunk * len(entries) / chunks
+		to := (chunk + 1) * len(entries) / chunks
+		for i := from; i < to; i++ {
+			entry := entries[i]
+			if !loadEntry(c, entry) {
+				continue
+			}
+			loaded.Add(1)
+
+			if sizes[i] <= maximum2 {
+				c.GetIfPresent(entry.Key)
+				c.GetIfPresent(entry.Key)
+				continue
+			}
+			if sizes[i] <= maximum1 {
+				c.GetIfPresent(entry.Key)
+				continue
+			}
+		}
+	})
+	return int(loaded.Load())
+}
+
+// loadEntry inserts the entry into the cache. It returns false if the entry has already expired.
+func loadEntry[K comparable, V any](c *Cache[K, V], entry Entry[K, V]) bool {
+	nowNano := c.cache.clock.NowNano()
+	if entry.ExpiresAtNano < nowNano {
+		return false
+	}
+	c.Set(entry.Key, entry.Value)
+	if c.cache.withExpiration && entry.ExpiresAtNano != unreachableExpiresAt {
+		expiresAfter := max(1, time.Duration(entry.ExpiresAtNano-nowNano))
+		c.SetExpiresAfter(entry.Key, expiresAfter)
+	}
+	if c.cache.withRefresh && entry.RefreshableAtNano != unreachableRefreshableAt {
+		refreshableAfter := max(1, time.Duration(entry.RefreshableAtNano-nowNano))
+		c.SetRefreshableAfter(entry.Key, refreshableAfter)
+	}
+	return true
 }
 
 // SaveCacheToFile atomically saves cache data to the given filePath.
 //
+// The data is written to a temporary file in the same directory, synced to stable storage and then
+// renamed over filePath, so a crash during SaveCacheToFile never destroys the previous snapshot.
+// The temporary files left by such a crash are removed by the next SaveCacheToFile, so concurrent calls
+// with the same filePath are not supported.
+// If SaveOptions.Generations is specified, the previous snapshots are rotated instead of being replaced.
+//
 // SaveCacheToFile may be called concurrently with other operations on the cache.
 //
 // The saved data may be loaded with LoadCacheFromFile.
 //
 // WARNING: Beware that this operation is performed within the eviction policy's exclusive lock.
 // While the operation is in progress further eviction maintenance will be halted.
-func SaveCacheToFile[K comparable, V any](c *Cache[K, V], filePath string) error {
+func SaveCacheToFile[K comparable, V any](c *Cache[K, V], filePath string, opts ...*SaveOptions[K, V]) error {
+	o := lastOptions(opts)
+
 	// Create dir if it doesn't exist.
 	dir := filepath.Dir(filePath)
 	if _, err := os.Stat(dir); err != nil {
@@ -112,44 +633,200 @@ func SaveCacheToFile[K comparable, V any](c *Cache[K, V], filePath string) error
 		}
 	}
 
-	file, err := os.Create(filePath)
+	return writeFileAtomically(filePath, o.Generations, func(w io.Writer) error {
+		return SaveCacheTo(c, w, o)
+	})
+}
+
+// writeFileAtomically writes the data to a temporary file, syncs it and renames it over filePath,
+// keeping the given number of previous generations of the file.
+func writeFileAtomically(filePath string, generations int, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(filePath)
+	tmpPrefix := filepath.Base(filePath) + ".tmp-"
+	removeTempFiles(dir, tmpPrefix)
+	file, err := os.CreateTemp(dir, tmpPrefix+"*")
 	if err != nil {
-		return fmt.Errorf("otter: create file %s: %w", filePath, err)
+		return fmt.Errorf("otter: create temp file in %s: %w", dir, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if err != nil {
+			//nolint:errcheck // the original error is more important
+			file.Close()
+			//nolint:errcheck // the original error is more important
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if err := write(file); err != nil {
+		return err
+	}
+	if err := file.Chmod(0o644); err != nil {
+		return fmt.Errorf("otter: chmod %s: %w", tmpPath, err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("otter: sync %s: %w", tmpPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("otter: close %s: %w", tmpPath, err)
+	}
+
+	if err := rotateGenerations(filePath, generations); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("otter: rename %s to %s: %w", tmpPath, filePath, err)
+	}
+	return syncDir(dir)
+}
+
+// removeTempFiles removes the temporary files left in dir by the writes that were interrupted by a crash.
+func removeTempFiles(dir, prefix string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			//nolint:errcheck // the file will be removed by the next write
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// rotateGenerations shifts filePath.1...filePath.(n-1) to filePath.2...filePath.n and
+// makes the current filePath the newest previous generation.
+//
+// filePath itself is hard-linked (if possible), so that it exists until it is replaced by the new snapshot.
+func rotateGenerations(filePath string, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("otter: stat %s: %w", filePath, err)
+	}
+
+	for i := n; i > 1; i-- {
+		from := generationPath(filePath, i-1)
+		to := generationPath(filePath, i)
+		if err := os.Rename(from, to); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("otter: rename %s to %s: %w", from, to, err)
+		}
+	}
+
+	newest := generationPath(filePath, 1)
+	if err := os.Remove(newest); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("otter: remove %s: %w", newest, err)
+	}
+	if err := os.Link(filePath, newest); err != nil {
+		// hard links are not supported, so fall back to renaming.
+		if err := os.Rename(filePath, newest); err != nil {
+			return fmt.Errorf("otter: rename %s to %s: %w", filePath, newest, err)
+		}
+	}
+	return nil
+}
+
+func generationPath(filePath string, generation int) string {
+	return filePath + "." + strconv.Itoa(generation)
+}
+
+// syncDir makes the renames in the directory durable.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// directories cannot be synced on windows.
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("otter: open dir %s: %w", dir, err)
 	}
 	//nolint:errcheck // it's ok
-	defer file.Close()
+	defer d.Close()
 
-	return SaveCacheTo(c, file)
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("otter: sync dir %s: %w", dir, err)
+	}
+	return nil
 }
 
 // SaveCacheTo atomically saves cache data to the given [io.Writer].
 //
 // SaveCacheToFile may be called concurrently with other operations on the cache.
 //
-// The saved data may be loaded with LoadCacheFrom.
+// The saved data may be loaded with LoadCacheFrom using the same Codec. The data is written
+// in a versioned format with a header identifying the key and value types and a trailing checksum.
+//
+// If several SaveOptions are specified, only the last non-nil one is used.
 //
 // WARNING: Beware that this operation is performed within the eviction policy's exclusive lock.
 // While the operation is in progress further eviction maintenance will be halted.
-func SaveCacheTo[K comparable, V any](c *Cache[K, V], w io.Writer) error {
-	enc := gob.NewEncoder(w)
+func SaveCacheTo[K comparable, V any](c *Cache[K, V], w io.Writer, opts ...*SaveOptions[K, V]) error {
+	o := lastOptions(opts)
+
+	var (
+		flags           uint16
+		ps              *policyState
+		entries         = c.Hottest()
+		entriesPerShard uint64
+	)
+	if o.PreservePolicy && c.cache.withEviction {
+		flags |= snapshotFlagPolicy
+		ps = &policyState{}
+		entries = c.cache.evictionOrder(true, ps)
+	}
+	if o.Shards > 1 {
+		flags |= snapshotFlagSharded
+		//nolint:gosec // there is no overflow
+		entriesPerShard = uint64((c.EstimatedSize() + o.Shards - 1) / o.Shards)
+	}
 
 	maximum := c.GetMaximum()
-	if err := enc.Encode(maximum); err != nil {
-		return fmt.Errorf("otter: encode maximum: %w", err)
+	sw := newSnapshotWriter(w)
+	err := sw.writeHeader(snapshotHeader{
+		version:     snapshotVersion,
+		flags:       flags,
+		fingerprint: typeFingerprint[K, V](),
+		maximum:     maximum,
+	}, o.Compressor, entriesPerShard)
+	if err != nil {
+		return fmt.Errorf("otter: write header: %w", err)
 	}
 
+	codec := o.getCodec()
+	var enc EntryEncoder[K, V]
 	size := uint64(0)
-	for entry := range c.Hottest() {
-		if size >= maximum {
-			break
+	for entry := range entries {
+		entry, ok := transformEntry(entry, o.Filter, o.Transform)
+		if !ok {
+			if ps != nil {
+				// the state of the dropped entry is not saved either.
+				ps.nodes = ps.nodes[:len(ps.nodes)-1]
+			}
+			continue
+		}
+		if enc == nil || sw.shardEnded() {
+			enc = codec.NewEncoder(sw)
 		}
-
 		if err := enc.Encode(entry); err != nil {
 			return fmt.Errorf("otter: encode entry: %w", err)
 		}
+		if err := sw.endFrame(); err != nil {
+			return fmt.Errorf("otter: write entry: %w", err)
+		}
 
 		size += uint64(entry.Weight)
+		if size >= maximum {
+			break
+		}
 	}
 
+	if err := sw.writeTrailer(ps); err != nil {
+		return fmt.Errorf("otter: write trailer: %w", err)
+	}
 	return nil
 }
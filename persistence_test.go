@@ -15,12 +15,21 @@
 package otter
 
 import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/maypok86/otter/v2/internal/deque"
 )
 
 func TestSaveLoadCache(t *testing.T) {
@@ -102,4 +111,629 @@ func TestSaveLoadCache(t *testing.T) {
 			require.Equal(t, refreshableAfter+1-sl, entry.RefreshableAfter())
 		}
 	})
+	t.Run("codecs", func(t *testing.T) {
+		t.Parallel()
+
+		codecs := map[string]Codec[int, string]{
+			"gob":    GobCodec[int, string]{},
+			"json":   JSONCodec[int, string]{},
+			"binary": BinaryCodec[int, string]{},
+		}
+		for name, codec := range codecs {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				const maximum = 10
+				c := Must(&Options[int, string]{
+					MaximumSize: maximum,
+				})
+				for i := 0; i < maximum; i++ {
+					c.Set(i, strconv.Itoa(i))
+				}
+
+				var buf bytes.Buffer
+				err := SaveCacheTo(c, &buf, &SaveOptions[int, string]{
+					Codec: codec,
+				})
+				require.NoError(t, err)
+
+				c = Must(&Options[int, string]{
+					MaximumSize: maximum,
+				})
+				err = LoadCacheFrom(c, &buf, &LoadOptions[int, string]{
+					Codec: codec,
+				})
+				require.NoError(t, err)
+				require.Equal(t, maximum, c.EstimatedSize())
+				for i := 0; i < maximum; i++ {
+					v, ok := c.GetIfPresent(i)
+					require.True(t, ok)
+					require.Equal(t, strconv.Itoa(i), v)
+				}
+			})
+		}
+	})
+}
+
+func TestLoadCache_InvalidSnapshot(t *testing.T) {
+	t.Parallel()
+
+	const maximum = 10
+	c := Must(&Options[int, int]{
+		MaximumSize: maximum,
+	})
+	for i := 0; i < maximum; i++ {
+		c.Set(i, i)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, SaveCacheTo(c, &buf))
+	snapshot := buf.Bytes()
+
+	load := func(t *testing.T, data []byte) error {
+		t.Helper()
+
+		c := Must(&Options[int, int]{
+			MaximumSize: maximum,
+		})
+		err := LoadCacheFrom(c, bytes.NewReader(data))
+		if err != nil {
+			require.Equal(t, 0, c.EstimatedSize())
+		}
+		return err
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, load(t, snapshot))
+	})
+	t.Run("bad_magic", func(t *testing.T) {
+		t.Parallel()
+
+		data := bytes.Clone(snapshot)
+		data[0] = 'X'
+		require.ErrorIs(t, load(t, data), ErrInvalidSnapshot)
+		require.ErrorIs(t, load(t, nil), ErrInvalidSnapshot)
+	})
+	t.Run("bad_version", func(t *testing.T) {
+		t.Parallel()
+
+		data := bytes.Clone(snapshot)
+		data[len(snapshotMagic)+1]++
+		require.ErrorIs(t, load(t, data), ErrUnsupportedSnapshotVersion)
+	})
+	t.Run("unknown_flags", func(t *testing.T) {
+		t.Parallel()
+
+		data := bytes.Clone(snapshot)
+		data[len(snapshotMagic)+2] |= 0x80
+		require.ErrorIs(t, load(t, data), ErrUnsupportedSnapshotVersion)
+	})
+	t.Run("type_mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		c := Must(&Options[string, int]{})
+		err := LoadCacheFrom(c, bytes.NewReader(snapshot))
+		require.ErrorIs(t, err, ErrSnapshotTypeMismatch)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		t.Parallel()
+
+		for i := 28; i < len(snapshot); i++ {
+			require.ErrorIs(t, load(t, snapshot[:i]), ErrCorruptedSnapshot, "length: %d", i)
+		}
+	})
+	t.Run("checksum", func(t *testing.T) {
+		t.Parallel()
+
+		data := bytes.Clone(snapshot)
+		data[len(data)-1]++
+		require.ErrorIs(t, load(t, data), ErrCorruptedSnapshot)
+	})
+	t.Run("corrupted_body", func(t *testing.T) {
+		t.Parallel()
+
+		codecs := map[string]Codec[int, int]{
+			"gob":    GobCodec[int, int]{},
+			"json":   JSONCodec[int, int]{},
+			"binary": BinaryCodec[int, int]{},
+		}
+		for name, codec := range codecs {
+			for _, so := range []*SaveOptions[int, int]{
+				{Codec: codec},
+				{Codec: codec, Compressor: GzipCompressor{}},
+				{Codec: codec, Shards: 3},
+				{Codec: codec, PreservePolicy: true},
+			} {
+				var buf bytes.Buffer
+				require.NoError(t, SaveCacheTo(c, &buf, so))
+				snapshot := buf.Bytes()
+
+				headerSize := len(snapshotMagic) + 2 + 2 + 8 + 8 + 1
+				if so.Compressor != nil {
+					headerSize += len(so.Compressor.Name())
+				}
+				// every flipped byte in the body and the trailer is detected,
+				// except for the metadata of the compressed stream, which doesn't affect the entries.
+				for i := headerSize; i < len(snapshot); i++ {
+					data := bytes.Clone(snapshot)
+					data[i] ^= 0xff
+					c := Must(&Options[int, int]{
+						MaximumSize: maximum,
+					})
+					err := LoadCacheFrom(c, bytes.NewReader(data), &LoadOptions[int, int]{
+						Codec: codec,
+					})
+					if err == nil && so.Compressor != nil {
+						require.Equal(t, maximum, c.EstimatedSize())
+						for k, v := range c.All() {
+							require.Equal(t, k, v)
+						}
+						continue
+					}
+					require.ErrorIs(t, err, ErrCorruptedSnapshot, "codec: %s, offset: %d", name, i)
+					require.Equal(t, 0, c.EstimatedSize())
+				}
+			}
+		}
+	})
+}
+
+type failingCodec[K comparable, V any] struct {
+	GobCodec[K, V]
+}
+
+func (failingCodec[K, V]) NewEncoder(w io.Writer) EntryEncoder[K, V] {
+	return failingEncoder[K, V]{}
+}
+
+type failingEncoder[K comparable, V any] struct{}
+
+func (failingEncoder[K, V]) Encode(entry Entry[K, V]) error {
+	return errors.New("failed to encode")
+}
+
+func TestLoadCache_LegacySnapshot(t *testing.T) {
+	t.Parallel()
+
+	const maximum = 10
+	mc := newManualClock()
+	c := Must(&Options[int, int]{
+		MaximumSize:      maximum,
+		ExpiryCalculator: ExpiryWriting[int, int](time.Hour),
+		Clock:            mc,
+	})
+	for i := 0; i < maximum; i++ {
+		c.Set(i, i)
+	}
+
+	// the format written by the previous versions of SaveCacheTo.
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	require.NoError(t, enc.Encode(c.GetMaximum()))
+	for entry := range c.Hottest() {
+		require.NoError(t, enc.Encode(entry))
+	}
+	legacy := buf.Bytes()
+
+	newCache := func() *Cache[int, int] {
+		return Must(&Options[int, int]{
+			MaximumSize:      maximum,
+			ExpiryCalculator: ExpiryWriting[int, int](time.Hour),
+			Clock:            mc,
+		})
+	}
+	loaded := newCache()
+	var report LoadReport
+	require.NoError(t, LoadCacheFrom(loaded, bytes.NewReader(legacy), &LoadOptions[int, int]{
+		Report: &report,
+	}))
+	require.Equal(t, LoadReport{Saved: maximum, Loaded: maximum}, report)
+	for i := 0; i < maximum; i++ {
+		entry, ok := loaded.GetEntryQuietly(i)
+		require.True(t, ok)
+		require.Equal(t, i, entry.Value)
+		require.Equal(t, time.Hour, entry.ExpiresAfter())
+	}
+
+	// a truncated legacy snapshot is rejected.
+	loaded = newCache()
+	err := LoadCacheFrom(loaded, bytes.NewReader(legacy[:len(legacy)-3]))
+	require.ErrorIs(t, err, ErrCorruptedSnapshot)
+	require.Equal(t, 0, loaded.EstimatedSize())
+}
+
+func TestSaveCacheToFile_Atomic(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "cache.snap")
+	newCache := func(values ...int) *Cache[int, int] {
+		c := Must(&Options[int, int]{})
+		for _, v := range values {
+			c.Set(v, v)
+		}
+		return c
+	}
+	loadKeys := func(path string) []int {
+		c := newCache()
+		require.NoError(t, LoadCacheFromFile(c, path))
+		keys := slices.Collect(c.Keys())
+		slices.Sort(keys)
+		return keys
+	}
+	opts := &SaveOptions[int, int]{Generations: 2}
+
+	require.NoError(t, SaveCacheToFile(newCache(1), filePath, opts))
+	require.NoError(t, SaveCacheToFile(newCache(1, 2), filePath, opts))
+	require.NoError(t, SaveCacheToFile(newCache(1, 2, 3), filePath, opts))
+	require.NoError(t, SaveCacheToFile(newCache(1, 2, 3, 4), filePath, opts))
+
+	require.Equal(t, []int{1, 2, 3, 4}, loadKeys(filePath))
+	require.Equal(t, []int{1, 2, 3}, loadKeys(filePath+".1"))
+	require.Equal(t, []int{1, 2}, loadKeys(filePath+".2"))
+	require.NoFileExists(t, filePath+".3")
+
+	err := SaveCacheToFile(newCache(5), filePath, &SaveOptions[int, int]{
+		Codec:       failingCodec[int, int]{},
+		Generations: 2,
+	})
+	require.Error(t, err)
+	require.Equal(t, []int{1, 2, 3, 4}, loadKeys(filePath))
+	require.Equal(t, []int{1, 2, 3}, loadKeys(filePath+".1"))
+
+	files, err := os.ReadDir(filepath.Dir(filePath))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	// the temporary files left by a crash are removed.
+	stale := filePath + ".tmp-123"
+	require.NoError(t, os.WriteFile(stale, []byte("partial"), 0o644))
+	require.NoError(t, SaveCacheToFile(newCache(1, 2, 3, 4, 5), filePath, opts))
+	require.NoFileExists(t, stale)
+	files, err = os.ReadDir(filepath.Dir(filePath))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+}
+
+func TestSaveLoadCache_PreservePolicy(t *testing.T) {
+	t.Parallel()
+
+	const maximum = 100
+	newCache := func() *Cache[int, int] {
+		return Must(&Options[int, int]{
+			MaximumSize: maximum,
+			Executor: func(fn func()) {
+				fn()
+			},
+		})
+	}
+	policyStates := func(c *Cache[int, int]) map[int]nodeState {
+		c.CleanUp()
+		c.cache.evictionMutex.Lock()
+		defer c.cache.evictionMutex.Unlock()
+
+		states := make(map[int]nodeState)
+		for n := range c.cache.nodes() {
+			states[n.Key()] = c.cache.evictionPolicy.nodeState(n)
+		}
+		return states
+	}
+
+	c := newCache()
+	for i := 0; i < 2*maximum; i++ {
+		c.Set(i, i)
+		for j := 0; j < i%7; j++ {
+			c.GetIfPresent(i)
+		}
+		c.CleanUp()
+	}
+	for k := range c.Keys() {
+		if k%3 == 0 {
+			c.GetIfPresent(k)
+		}
+	}
+	want := policyStates(c)
+
+	var buf bytes.Buffer
+	require.NoError(t, SaveCacheTo(c, &buf, &SaveOptions[int, int]{
+		PreservePolicy: true,
+	}))
+
+	loaded := newCache()
+	require.NoError(t, LoadCacheFrom(loaded, &buf))
+	got := policyStates(loaded)
+	require.Len(t, got, len(want))
+
+	queues := make(map[uint8]int)
+	for k, w := range want {
+		g, ok := got[k]
+		require.True(t, ok, "key: %d", k)
+		require.Equal(t, w.queueType, g.queueType, "key: %d", k)
+		require.GreaterOrEqual(t, g.frequency, w.frequency, "key: %d", k)
+		queues[w.queueType]++
+	}
+	require.Len(t, queues, 3)
+	require.Equal(t, c.cache.evictionPolicy.windowMaximum, loaded.cache.evictionPolicy.windowMaximum)
+	require.Equal(t, c.cache.evictionPolicy.mainProtectedMaximum, loaded.cache.evictionPolicy.mainProtectedMaximum)
+
+	queueKeys := func(d *deque.Linked[int, int]) []int {
+		var keys []int
+		for n := range d.All() {
+			keys = append(keys, n.Key())
+		}
+		return keys
+	}
+	require.Equal(t, queueKeys(c.cache.evictionPolicy.protected), queueKeys(loaded.cache.evictionPolicy.protected))
+	require.Equal(t, queueKeys(c.cache.evictionPolicy.probation), queueKeys(loaded.cache.evictionPolicy.probation))
+	require.Equal(t, queueKeys(c.cache.evictionPolicy.window), queueKeys(loaded.cache.evictionPolicy.window))
+
+	buf.Reset()
+	require.NoError(t, SaveCacheTo(c, &buf, &SaveOptions[int, int]{
+		PreservePolicy: true,
+		Shards:         4,
+	}))
+	sharded := newCache()
+	require.NoError(t, LoadCacheFrom(sharded, &buf, &LoadOptions[int, int]{
+		Parallelism: 4,
+	}))
+	sharded.CleanUp()
+	require.Equal(t, queueKeys(c.cache.evictionPolicy.protected), queueKeys(sharded.cache.evictionPolicy.protected))
+	require.Equal(t, queueKeys(c.cache.evictionPolicy.probation), queueKeys(sharded.cache.evictionPolicy.probation))
+	require.Equal(t, queueKeys(c.cache.evictionPolicy.window), queueKeys(sharded.cache.evictionPolicy.window))
+}
+
+func TestSaveLoadCache_Sharded(t *testing.T) {
+	t.Parallel()
+
+	const size = 1000
+	fs := &fakeSource{}
+	newCache := func(maximum int) *Cache[int, string] {
+		return Must(&Options[int, string]{
+			MaximumSize:      maximum,
+			ExpiryCalculator: ExpiryWriting[int, string](time.Hour),
+			Clock:            fs,
+		})
+	}
+	c := newCache(size)
+	for i := 0; i < size; i++ {
+		c.Set(i, strconv.Itoa(i))
+	}
+	for i := 0; i < size/10; i++ {
+		c.SetExpiresAfter(i, time.Minute)
+	}
+
+	// the entries that expire after saving are not loaded.
+	var buf bytes.Buffer
+	require.NoError(t, SaveCacheTo(c, &buf, &SaveOptions[int, string]{Shards: 4}))
+	fs.Sleep(2 * time.Minute)
+	loaded := newCache(size)
+	require.NoError(t, LoadCacheFrom(loaded, &buf))
+	const remaining = size - size/10
+	require.Equal(t, remaining, loaded.EstimatedSize())
+
+	for name, compressor := range map[string]Compressor{
+		"plain": nil,
+		"gzip":  GzipCompressor{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			require.NoError(t, SaveCacheTo(c, &buf, &SaveOptions[int, string]{
+				Codec:      BinaryCodec[int, string]{},
+				Compressor: compressor,
+				Shards:     8,
+			}))
+			snapshot := buf.Bytes()
+
+			load := func(t *testing.T, maximum int, data []byte) (*Cache[int, string], error) {
+				t.Helper()
+
+				loaded := newCache(maximum)
+				return loaded, LoadCacheFrom(loaded, bytes.NewReader(data), &LoadOptions[int, string]{
+					Codec:       BinaryCodec[int, string]{},
+					Parallelism: 4,
+				})
+			}
+
+			loaded, err := load(t, size, snapshot)
+			require.NoError(t, err)
+			require.Equal(t, remaining, loaded.EstimatedSize())
+			for k, v := range loaded.All() {
+				require.Equal(t, strconv.Itoa(k), v)
+			}
+
+			// the maximum is respected.
+			loaded, err = load(t, size/2, snapshot)
+			require.NoError(t, err)
+			loaded.CleanUp()
+			require.LessOrEqual(t, loaded.EstimatedSize(), size/2)
+
+			for i := 28; i < len(snapshot); i += 7 {
+				_, err := load(t, size, snapshot[:i])
+				require.ErrorIs(t, err, ErrCorruptedSnapshot, "length: %d", i)
+			}
+		})
+	}
+}
+
+func TestSaveLoadCache_Transform(t *testing.T) {
+	t.Parallel()
+
+	const maximum = 100
+	c := Must(&Options[int, string]{
+		MaximumSize: maximum,
+	})
+	for i := 0; i < maximum; i++ {
+		c.Set(i, strconv.Itoa(i))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, SaveCacheTo(c, &buf, &SaveOptions[int, string]{
+		PreservePolicy: true,
+		Filter: func(entry Entry[int, string]) bool {
+			return entry.Key%2 == 0
+		},
+		Transform: func(entry Entry[int, string]) (Entry[int, string], bool) {
+			if entry.Key%10 == 0 {
+				return entry, false
+			}
+			entry.Value = "v1:" + entry.Value
+			return entry, true
+		},
+	}))
+
+	loaded := Must(&Options[int, string]{
+		MaximumWeight: 30,
+		Weigher: func(key int, value string) uint32 {
+			return 1
+		},
+	})
+	require.NoError(t, LoadCacheFrom(loaded, &buf, &LoadOptions[int, string]{
+		Filter: func(entry Entry[int, string]) bool {
+			return entry.Key != 2
+		},
+		Transform: func(entry Entry[int, string]) (Entry[int, string], bool) {
+			entry.Value = strings.Replace(entry.Value, "v1:", "v2:", 1)
+			entry.Weight = 2
+			return entry, true
+		},
+	}))
+
+	// only 15 entries with the weight of 2 fit into the maximum.
+	loaded.CleanUp()
+	require.Equal(t, 15, loaded.EstimatedSize())
+	for k, v := range loaded.All() {
+		require.Equal(t, 0, k%2)
+		require.NotEqual(t, 0, k%10)
+		require.NotEqual(t, 2, k)
+		require.Equal(t, "v2:"+strconv.Itoa(k), v)
+	}
+}
+
+func TestSaveLoadCache_ExpiryPolicy(t *testing.T) {
+	t.Parallel()
+
+	const size = 10
+	mc := newManualClock()
+	c := Must(&Options[int, int]{
+		ExpiryCalculator: ExpiryWriting[int, int](time.Hour),
+		Clock:            mc,
+		Logger:           &NoopLogger{},
+	})
+	for i := 0; i < size; i++ {
+		c.Set(i, i)
+	}
+	c.SetExpiresAfter(0, 10*time.Minute)
+	c.SetExpiresAfter(1, 2*time.Hour)
+
+	var buf bytes.Buffer
+	require.NoError(t, SaveCacheTo(c, &buf))
+	snapshot := buf.Bytes()
+	mc.advance(30 * time.Minute)
+
+	filter := func(entry Entry[int, int]) bool {
+		return entry.Key != size-1
+	}
+	for _, tt := range []struct {
+		name    string
+		policy  LoadExpiryPolicy
+		want    map[int]time.Duration
+		report  LoadReport
+		options *Options[int, int]
+	}{
+		{
+			name:   "keep_deadline",
+			policy: LoadExpiryKeepDeadline,
+			want: map[int]time.Duration{
+				1: 90 * time.Minute,
+				2: 30 * time.Minute,
+			},
+			report: LoadReport{Saved: size, Loaded: size - 2, Expired: 1, Skipped: 1},
+		},
+		{
+			name:   "keep_remaining",
+			policy: LoadExpiryKeepRemaining,
+			want: map[int]time.Duration{
+				0: 10 * time.Minute,
+				1: 2 * time.Hour,
+				2: time.Hour,
+			},
+			report: LoadReport{Saved: size, Loaded: size - 1, Skipped: 1},
+		},
+		{
+			name:   "recompute",
+			policy: LoadExpiryRecompute,
+			want: map[int]time.Duration{
+				0: 5 * time.Minute,
+				1: 5 * time.Minute,
+				2: 5 * time.Minute,
+			},
+			report: LoadReport{Saved: size, Loaded: size - 1, Skipped: 1},
+			options: &Options[int, int]{
+				ExpiryCalculator: ExpiryCreating[int, int](5 * time.Minute),
+			},
+		},
+		{
+			name:   "truncate",
+			policy: LoadExpiryKeepRemaining,
+			report: LoadReport{Saved: size, Loaded: 3, Truncated: size - 3},
+			options: &Options[int, int]{
+				MaximumSize:      3,
+				ExpiryCalculator: ExpiryWriting[int, int](time.Hour),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := tt.options
+			if o == nil {
+				o = &Options[int, int]{
+					ExpiryCalculator: ExpiryWriting[int, int](time.Hour),
+				}
+			}
+			o.Clock = mc
+			o.Logger = &NoopLogger{}
+			loaded := Must(o)
+
+			var report LoadReport
+			opts := &LoadOptions[int, int]{
+				ExpiryPolicy: tt.policy,
+				Report:       &report,
+			}
+			if o.MaximumSize == 0 {
+				opts.Filter = filter
+			}
+			require.NoError(t, LoadCacheFrom(loaded, bytes.NewReader(snapshot), opts))
+			require.Equal(t, tt.report, report)
+			require.Equal(t, report.Loaded, loaded.EstimatedSize())
+
+			for k, want := range tt.want {
+				entry, ok := loaded.GetEntryQuietly(k)
+				require.True(t, ok, "key: %d", k)
+				require.Equal(t, want, entry.ExpiresAfter(), "key: %d", k)
+			}
+			if tt.policy == LoadExpiryKeepDeadline {
+				_, ok := loaded.GetIfPresent(0)
+				require.False(t, ok)
+			}
+		})
+	}
+
+	t.Run("without_expiration", func(t *testing.T) {
+		t.Parallel()
+
+		// the expired entries are skipped even if the cache doesn't use expiration.
+		loaded := Must(&Options[int, int]{
+			Clock: mc,
+		})
+		var report LoadReport
+		require.NoError(t, LoadCacheFrom(loaded, bytes.NewReader(snapshot), &LoadOptions[int, int]{
+			Report: &report,
+		}))
+		require.Equal(t, LoadReport{Saved: size, Loaded: size - 1, Expired: 1}, report)
+		_, ok := loaded.GetIfPresent(0)
+		require.False(t, ok)
+	})
 }
@@ -90,15 +90,7 @@ func (p *policy[K, V]) add(n node.Node[K, V], evictNode func(n node.Node[K, V],
 
 	p.weightedSize += nodeWeight
 	p.windowWeightedSize += nodeWeight
-	if p.weightedSize >= p.maximum>>1 {
-		// Lazily initialize when close to the maximum
-		capacity := p.maximum
-		if p.isWeighted {
-			//nolint:gosec // there's no overflow
-			capacity = uint64(p.window.Len()) + uint64(p.probation.Len()) + uint64(p.protected.Len())
-		}
-		p.sketch.ensureCapacity(capacity)
-	}
+	p.ensureSketchCapacity()
 
 	p.sketch.increment(n.Key())
 	p.missesInSample++
@@ -118,6 +110,20 @@ func (p *policy[K, V]) add(n node.Node[K, V], evictNode func(n node.Node[K, V],
 	}
 }
 
+func (p *policy[K, V]) ensureSketchCapacity() {
+	if p.weightedSize < p.maximum>>1 {
+		return
+	}
+
+	// Lazily initialize when close to the maximum
+	capacity := p.maximum
+	if p.isWeighted {
+		//nolint:gosec // there's no overflow
+		capacity = uint64(p.window.Len()) + uint64(p.probation.Len()) + uint64(p.protected.Len())
+	}
+	p.sketch.ensureCapacity(capacity)
+}
+
 func (p *policy[K, V]) update(n, old node.Node[K, V], evictNode func(n node.Node[K, V], nowNanos int64)) {
 	nodeWeight := uint64(n.Weight())
 	p.updateNode(n, old)
@@ -155,7 +161,12 @@ func (p *policy[K, V]) updateNode(n, old node.Node[K, V]) {
 
 	switch {
 	case n.InWindow():
-		p.window.UpdateNode(n, old)
+		if p.window.Contains(old) {
+			p.window.UpdateNode(n, old)
+		} else {
+			// the addition of the old node was ignored, because it had already been replaced
+			p.window.PushBack(n)
+		}
 	case n.InMainProbation():
 		p.probation.UpdateNode(n, old)
 	default:
@@ -540,3 +551,76 @@ func reorder[K comparable, V any](d *deque.Linked[K, V], n node.Node[K, V]) {
 		d.MoveToBack(n)
 	}
 }
+
+// nodeState is the state of a node in the eviction policy that is preserved in snapshots.
+type nodeState struct {
+	frequency uint8
+	queueType uint8
+}
+
+// policyState is the state of the eviction policy that is preserved in snapshots.
+type policyState struct {
+	windowMaximum        uint64
+	mainProtectedMaximum uint64
+	nodes                []nodeState
+}
+
+func (p *policy[K, V]) nodeState(n node.Node[K, V]) nodeState {
+	return nodeState{
+		//nolint:gosec // the frequency is limited to 15
+		frequency: uint8(p.sketch.frequency(n.Key())),
+		queueType: n.GetQueueType(),
+	}
+}
+
+// restore restores the saved state of the eviction policy. The nodes are ordered from the hottest to the coldest.
+//
+// The sizes of the queues are restored only if the policy has the same maximum as the saved one.
+func (p *policy[K, V]) restore(maximum uint64, ps policyState, nodes []node.Node[K, V], states []nodeState) {
+	if maximum == p.maximum && ps.windowMaximum+ps.mainProtectedMaximum <= maximum {
+		p.windowMaximum = ps.windowMaximum
+		p.mainProtectedMaximum = ps.mainProtectedMaximum
+	}
+	p.ensureSketchCapacity()
+
+	// the coldest nodes are pushed first, so that they end up at the heads of the queues.
+	for i := len(nodes) - 1; i >= 0; i-- {
+		p.restoreNode(nodes[i], states[i])
+	}
+}
+
+func (p *policy[K, V]) restoreNode(n node.Node[K, V], state nodeState) {
+	key := n.Key()
+	for frequency := p.sketch.frequency(key); frequency < uint64(state.frequency); frequency++ {
+		p.sketch.increment(key)
+	}
+
+	// ignore the nodes whose addition has not been processed yet
+	nodeWeight := uint64(n.Weight())
+	switch {
+	case n.InWindow() && p.window.Contains(n):
+		p.window.Delete(n)
+		p.windowWeightedSize -= nodeWeight
+	case n.InMainProbation() && p.probation.Contains(n):
+		p.probation.Delete(n)
+	case n.InMainProtected() && p.protected.Contains(n):
+		p.protected.Delete(n)
+		p.mainProtectedWeightedSize -= nodeWeight
+	default:
+		return
+	}
+
+	switch state.queueType {
+	case node.InMainProbationQueue:
+		p.probation.PushBack(n)
+		n.MakeMainProbation()
+	case node.InMainProtectedQueue:
+		p.protected.PushBack(n)
+		p.mainProtectedWeightedSize += nodeWeight
+		n.MakeMainProtected()
+	default:
+		p.window.PushBack(n)
+		p.windowWeightedSize += nodeWeight
+		n.MakeWindow()
+	}
+}
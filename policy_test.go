@@ -29,3 +29,24 @@ func TestPolicy_SetMaximumSize(t *testing.T) {
 		p.setMaximumSize(10)
 	})
 }
+
+func TestPolicy_UpdateIgnoredAddition(t *testing.T) {
+	t.Parallel()
+
+	c := Must(&Options[int, int]{
+		MaximumSize: 100,
+	})
+	for i := 0; i < 10; i++ {
+		c.Set(i, i)
+	}
+	// the addition of the first node is ignored, because it's replaced before the maintenance.
+	c.Set(1, 100)
+	c.CleanUp()
+
+	keys := make(map[int]int)
+	for e := range c.Hottest() {
+		keys[e.Key] = e.Value
+	}
+	require.Len(t, keys, 10)
+	require.Equal(t, 100, keys[1])
+}
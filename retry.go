@@ -0,0 +1,166 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+	defaultRetryMultiplier     = 2
+)
+
+// RetryPolicy configures the retries of the failed calls of Loader and BulkLoader made by Cache.Get,
+// Cache.BulkGet, Cache.Refresh, Cache.BulkRefresh and the background refreshes.
+//
+// The delay before the n-th retry is InitialBackoff * Multiplier^(n-1), but not more than MaxBackoff,
+// and it's reduced by a random fraction of up to Jitter. The cache waits for the delay using Options.Clock
+// if it implements [Sleeper], or a real timer otherwise, and stops retrying if the context of the load is canceled.
+//
+// ErrNotFound and panics are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of calls of the loader for a single load, including the first one.
+	//
+	// If MaxAttempts is less than 2, the failed loads are not retried.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	//
+	// If InitialBackoff is not positive, 100ms is used.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between the attempts.
+	//
+	// If MaxBackoff is not positive, 10s is used.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the delay grows after each retry.
+	//
+	// If Multiplier is less than 1, 2 is used.
+	Multiplier float64
+	// Jitter is the maximum fraction of the delay, from 0 to 1, that is randomly subtracted from it,
+	// so that the retries of many keys do not hit the data source at the same time.
+	//
+	// By default, the delays are not randomized.
+	Jitter float64
+	// IsRetryable reports whether a load that failed with the err should be retried.
+	//
+	// By default, all errors are retried, except for the errors of a canceled context
+	// (context.Canceled and context.DeadlineExceeded).
+	IsRetryable func(err error) bool
+}
+
+type retrier struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+	jitter         float64
+	isRetryable    func(err error) bool
+	clock          timeSource
+	debugLogger    DebugLogger
+}
+
+// newRetrier returns a retrier based on the policy or nil if the failed loads shouldn't be retried.
+func newRetrier(p *RetryPolicy, clock timeSource, debugLogger DebugLogger) *retrier {
+	if p == nil || p.MaxAttempts < 2 {
+		return nil
+	}
+
+	r := &retrier{
+		maxAttempts:    p.MaxAttempts,
+		initialBackoff: p.InitialBackoff,
+		maxBackoff:     p.MaxBackoff,
+		multiplier:     p.Multiplier,
+		jitter:         p.Jitter,
+		isRetryable:    p.IsRetryable,
+		clock:          clock,
+		debugLogger:    debugLogger,
+	}
+	if r.initialBackoff <= 0 {
+		r.initialBackoff = defaultRetryInitialBackoff
+	}
+	if r.maxBackoff <= 0 {
+		r.maxBackoff = defaultRetryMaxBackoff
+	}
+	if r.multiplier < 1 {
+		r.multiplier = defaultRetryMultiplier
+	}
+	if r.isRetryable == nil {
+		r.isRetryable = isRetryableByDefault
+	}
+	return r
+}
+
+func isRetryableByDefault(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func (r *retrier) shouldRetry(err error) bool {
+	var pe *panicError
+	if errors.Is(err, ErrNotFound) || errors.As(err, &pe) {
+		return false
+	}
+	return r.isRetryable(err)
+}
+
+// backoff returns the delay before the retry-th retry.
+func (r *retrier) backoff(retry int) time.Duration {
+	delay := float64(r.initialBackoff)
+	for i := 1; i < retry && delay < float64(r.maxBackoff); i++ {
+		delay *= r.multiplier
+	}
+	delay = min(delay, float64(r.maxBackoff))
+	if r.jitter > 0 {
+		//nolint:gosec // the jitter doesn't need a cryptographically secure random number
+		delay -= delay * r.jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// withRetries returns a function that calls load until it succeeds, fails with an error
+// that shouldn't be retried or the attempts run out. If r is nil, load is returned as is.
+func withRetries[A, R any](
+	r *retrier,
+	load func(ctx context.Context, arg A) (R, error),
+) func(ctx context.Context, arg A) (R, error) {
+	if r == nil {
+		return load
+	}
+
+	return func(ctx context.Context, arg A) (R, error) {
+		for attempt := 1; ; attempt++ {
+			res, err := load(ctx, arg)
+			if err == nil || attempt >= r.maxAttempts || !r.shouldRetry(err) {
+				return res, err
+			}
+
+			delay := r.backoff(attempt)
+			if r.debugLogger != nil && r.debugLogger.DebugEnabled(ctx) {
+				r.debugLogger.Debug(ctx, "Retrying a failed load",
+					slog.Int("attempt", attempt),
+					slog.Duration("delay", delay),
+					slog.String("error", err.Error()),
+				)
+			}
+			if r.clock.SleepContext(ctx, delay) != nil {
+				return res, err
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrier_Backoff(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, newRetrier(nil, &realSource{}, nil))
+	require.Nil(t, newRetrier(&RetryPolicy{MaxAttempts: 1}, &realSource{}, nil))
+
+	r := newRetrier(&RetryPolicy{MaxAttempts: 5}, &realSource{}, nil)
+	require.Equal(t, 100*time.Millisecond, r.backoff(1))
+	require.Equal(t, 200*time.Millisecond, r.backoff(2))
+	require.Equal(t, 400*time.Millisecond, r.backoff(3))
+	require.Equal(t, 10*time.Second, r.backoff(100))
+
+	r = newRetrier(&RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     3,
+		Jitter:         0.5,
+	}, &realSource{}, nil)
+	for i := 0; i < 100; i++ {
+		d := r.backoff(2)
+		require.GreaterOrEqual(t, d, 1500*time.Millisecond)
+		require.LessOrEqual(t, d, 3*time.Second)
+		d = r.backoff(3)
+		require.GreaterOrEqual(t, d, 2500*time.Millisecond)
+		require.LessOrEqual(t, d, 5*time.Second)
+	}
+}
+
+func TestRetrier_ShouldRetry(t *testing.T) {
+	t.Parallel()
+
+	r := newRetrier(&RetryPolicy{MaxAttempts: 2}, &realSource{}, nil)
+	require.True(t, r.shouldRetry(errors.New("failed")))
+	require.False(t, r.shouldRetry(ErrNotFound))
+	require.False(t, r.shouldRetry(fmt.Errorf("wrapped: %w", ErrNotFound)))
+	require.False(t, r.shouldRetry(newPanicError("boom")))
+	require.False(t, r.shouldRetry(context.Canceled))
+	require.False(t, r.shouldRetry(context.DeadlineExceeded))
+
+	permanent := errors.New("permanent")
+	r = newRetrier(&RetryPolicy{
+		MaxAttempts: 2,
+		IsRetryable: func(err error) bool {
+			return !errors.Is(err, permanent)
+		},
+	}, &realSource{}, nil)
+	require.True(t, r.shouldRetry(context.Canceled))
+	require.False(t, r.shouldRetry(permanent))
+	require.False(t, r.shouldRetry(ErrNotFound))
+}
+
+func TestCache_RetryGet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := &fakeSource{}
+	c := Must(&Options[int, int]{
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Second,
+		},
+		Clock: fs,
+	})
+
+	loadErr := errors.New("failed")
+	var calls atomic.Int64
+	v, err := c.Get(ctx, 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		if calls.Add(1) < 3 {
+			return 0, loadErr
+		}
+		return key, nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	require.Equal(t, int64(3), calls.Load())
+
+	// the attempts run out.
+	start := fs.NowNano()
+	calls.Store(0)
+	_, err = c.Get(ctx, 2, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		calls.Add(1)
+		return 0, loadErr
+	}))
+	require.ErrorIs(t, err, loadErr)
+	require.Equal(t, int64(3), calls.Load())
+	require.Equal(t, int64(3*time.Second), fs.NowNano()-start)
+
+	// ErrNotFound is not retried.
+	calls.Store(0)
+	_, err = c.Get(ctx, 3, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		calls.Add(1)
+		return 0, ErrNotFound
+	}))
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, int64(1), calls.Load())
+}
+
+// sleepingClock is a user-defined Clock that advances its time instead of sleeping.
+type sleepingClock struct {
+	now    atomic.Int64
+	mutex  sync.Mutex
+	sleeps []time.Duration
+}
+
+func (sc *sleepingClock) NowNano() int64 {
+	return sc.now.Load()
+}
+
+func (sc *sleepingClock) Tick(duration time.Duration) <-chan time.Time {
+	return nil
+}
+
+func (sc *sleepingClock) Sleep(ctx context.Context, duration time.Duration) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	sc.sleeps = append(sc.sleeps, duration)
+	sc.now.Add(int64(duration))
+	return ctx.Err()
+}
+
+func TestCache_RetryWithUserClock(t *testing.T) {
+	t.Parallel()
+
+	sc := &sleepingClock{}
+	c := Must(&Options[int, int]{
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Hour,
+			MaxBackoff:     10 * time.Hour,
+		},
+		Clock: sc,
+	})
+
+	loadErr := errors.New("failed")
+	var calls atomic.Int64
+	// the backoff of hours doesn't take real time.
+	_, err := c.Get(context.Background(), 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		calls.Add(1)
+		return 0, loadErr
+	}))
+	require.ErrorIs(t, err, loadErr)
+	require.Equal(t, int64(3), calls.Load())
+	require.Equal(t, []time.Duration{time.Hour, 2 * time.Hour}, sc.sleeps)
+	require.Equal(t, int64(3*time.Hour), sc.NowNano())
+}
+
+func TestCache_RetryGetContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	c := Must(&Options[int, int]{
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Hour,
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	loadErr := errors.New("failed")
+	var calls atomic.Int64
+	_, err := c.Get(ctx, 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		calls.Add(1)
+		cancel()
+		return 0, loadErr
+	}))
+	require.ErrorIs(t, err, loadErr)
+	require.Equal(t, int64(1), calls.Load())
+}
+
+func TestCache_RetryBulkGet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := Must(&Options[int, int]{
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+		},
+	})
+
+	var calls atomic.Int64
+	result, err := c.BulkGet(ctx, []int{1, 2}, BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		if calls.Add(1) == 1 {
+			return nil, errors.New("failed")
+		}
+		return map[int]int{1: 1, 2: 2}, nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 1, 2: 2}, result)
+	require.Equal(t, int64(2), calls.Load())
+}
+
+func TestCache_RetryRefresh(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := Must(&Options[int, int]{
+		RefreshCalculator: RefreshWriting[int, int](time.Hour),
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+		},
+	})
+	c.Set(1, 1)
+	c.Set(2, 2)
+
+	var calls atomic.Int64
+	res := <-c.Refresh(ctx, 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		if calls.Add(1) == 1 {
+			return 0, errors.New("failed")
+		}
+		return 10, nil
+	}))
+	require.NoError(t, res.Err)
+	require.Equal(t, 10, res.Value)
+	require.Equal(t, int64(2), calls.Load())
+
+	fbl := &flakyBulkLoader{}
+	results := <-c.BulkRefresh(ctx, []int{2}, fbl)
+	require.Equal(t, []RefreshResult[int, int]{{Key: 2, Value: 20}}, results)
+	// the old values are passed to every attempt.
+	require.Equal(t, [][]int{{2}, {2}}, fbl.oldValues)
+}
+
+// flakyBulkLoader fails the first reload.
+type flakyBulkLoader struct {
+	oldValues [][]int
+}
+
+func (fbl *flakyBulkLoader) BulkLoad(ctx context.Context, keys []int) (map[int]int, error) {
+	panic("unreachable")
+}
+
+func (fbl *flakyBulkLoader) BulkReload(ctx context.Context, keys []int, oldValues []int) (map[int]int, error) {
+	fbl.oldValues = append(fbl.oldValues, oldValues)
+	if len(fbl.oldValues) == 1 {
+		return nil, errors.New("failed")
+	}
+	result := make(map[int]int, len(keys))
+	for i, k := range keys {
+		result[k] = oldValues[i] * 10
+	}
+	return result, nil
+}
@@ -0,0 +1,599 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+)
+
+// The snapshot format is:
+//
+//	header:      magic [8]byte | version uint16 | flags uint16 | fingerprint uint64 | maximum uint64 | compression
+//	compression: uint8(len) | name [len]byte
+//	body:        frame* | uvarint(0)
+//	frame:       uvarint(len) | payload [len]byte
+//	policy:      windowMaximum uint64 | mainProtectedMaximum uint64 | (frequency uint8 | queue uint8)*count
+//	trailer:     count uint64 | checksum uint32
+//
+// All fixed-size integers are big-endian. Every frame contains the output of a single EntryEncoder.Encode call.
+// The policy section is present only if the snapshotFlagPolicy flag is set and contains
+// the eviction policy state of every entry in the order of the frames.
+// If the compression name is not empty, the body and the trailer are compressed using the named Compressor.
+// The checksum is the CRC-32C of everything (uncompressed) that precedes it.
+//
+// If the snapshotFlagSharded flag is set, the body is split into independently encoded and compressed shards,
+// which can be decoded concurrently:
+//
+//	body:        shard* | uvarint(0)
+//	shard:       uvarint(count) | uvarint(len) | data [len]byte
+//
+// The data of a shard contains count frames and the terminating uvarint(0), compressed using the named Compressor.
+// The policy section and the trailer are not compressed, and the checksum covers the stored (compressed) data.
+const (
+	snapshotMagic   = "OTTERSNP"
+	snapshotVersion = uint16(1)
+	maxFrameSize    = math.MaxInt32
+)
+
+const (
+	// snapshotFlagPolicy means that the snapshot contains the policy section.
+	snapshotFlagPolicy uint16 = 1 << iota
+	// snapshotFlagSharded means that the body of the snapshot is split into shards.
+	snapshotFlagSharded
+
+	knownSnapshotFlags = snapshotFlagPolicy | snapshotFlagSharded
+)
+
+const (
+	// ErrInvalidSnapshot is returned when loading data that is not an otter snapshot.
+	ErrInvalidSnapshot strError = "otter: invalid snapshot"
+	// ErrUnsupportedSnapshotVersion is returned when loading a snapshot written in an unknown format version.
+	ErrUnsupportedSnapshotVersion strError = "otter: unsupported snapshot version"
+	// ErrSnapshotTypeMismatch is returned when loading a snapshot that was saved from a cache
+	// with different key or value types.
+	ErrSnapshotTypeMismatch strError = "otter: snapshot key/value types mismatch"
+	// ErrUnknownSnapshotCompression is returned when loading a snapshot compressed with an unknown Compressor.
+	ErrUnknownSnapshotCompression strError = "otter: unknown snapshot compression"
+	// ErrCorruptedSnapshot is returned when loading a truncated snapshot, a snapshot whose checksum doesn't match
+	// or a snapshot whose entries cannot be decoded.
+	ErrCorruptedSnapshot strError = "otter: snapshot is corrupted"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type snapshotHeader struct {
+	version     uint16
+	flags       uint16
+	fingerprint uint64
+	maximum     uint64
+	compression string
+}
+
+// typeFingerprint returns a hash identifying the key and value types of the cache.
+func typeFingerprint[K comparable, V any]() uint64 {
+	h := fnv.New64a()
+	//nolint:errcheck // hash.Hash never returns an error
+	_, _ = io.WriteString(h, reflect.TypeFor[K]().String())
+	//nolint:errcheck // hash.Hash never returns an error
+	_, _ = h.Write([]byte{0})
+	//nolint:errcheck // hash.Hash never returns an error
+	_, _ = io.WriteString(h, reflect.TypeFor[V]().String())
+	return h.Sum64()
+}
+
+// snapshotWriter writes the snapshot format and computes its checksum.
+type snapshotWriter struct {
+	w     *bufio.Writer
+	out   io.Writer
+	cw    io.WriteCloser
+	crc   hash.Hash32
+	frame bytes.Buffer
+	buf   []byte
+	count uint64
+	// the state of the current shard, if the snapshot is sharded.
+	sharded         bool
+	shardCompressor Compressor
+	shard           bytes.Buffer
+	shardOut        io.Writer
+	shardCW         io.WriteCloser
+	shardCount      uint64
+	entriesPerShard uint64
+}
+
+func newSnapshotWriter(w io.Writer) *snapshotWriter {
+	bw := bufio.NewWriter(w)
+	return &snapshotWriter{
+		w:   bw,
+		out: bw,
+		crc: crc32.New(crcTable),
+		buf: make([]byte, 0, 32),
+	}
+}
+
+func (sw *snapshotWriter) write(b []byte) error {
+	//nolint:errcheck // hash.Hash never returns an error
+	_, _ = sw.crc.Write(b)
+	_, err := sw.out.Write(b)
+	return err
+}
+
+// writeHeader writes the header. If compressor is not nil, the rest of the snapshot is compressed.
+//
+// If the snapshot is sharded, a new shard is started after every entriesPerShard entries.
+func (sw *snapshotWriter) writeHeader(h snapshotHeader, compressor Compressor, entriesPerShard uint64) error {
+	if compressor != nil {
+		h.compression = compressor.Name()
+	}
+	if len(h.compression) > math.MaxUint8 {
+		return fmt.Errorf("otter: too long compression name: %q", h.compression)
+	}
+
+	b := append(sw.buf[:0], snapshotMagic...)
+	b = binary.BigEndian.AppendUint16(b, h.version)
+	b = binary.BigEndian.AppendUint16(b, h.flags)
+	b = binary.BigEndian.AppendUint64(b, h.fingerprint)
+	b = binary.BigEndian.AppendUint64(b, h.maximum)
+	b = append(b, uint8(len(h.compression)))
+	b = append(b, h.compression...)
+	if err := sw.write(b); err != nil {
+		return err
+	}
+
+	if h.flags&snapshotFlagSharded != 0 {
+		// every shard is compressed separately.
+		sw.sharded = true
+		sw.shardCompressor = compressor
+		sw.entriesPerShard = max(1, entriesPerShard)
+		return nil
+	}
+	if compressor != nil {
+		cw, err := compressor.NewWriter(sw.w)
+		if err != nil {
+			return fmt.Errorf("otter: create %s writer: %w", h.compression, err)
+		}
+		sw.cw = cw
+		sw.out = cw
+	}
+	return nil
+}
+
+// Write buffers the payload of the current frame.
+func (sw *snapshotWriter) Write(p []byte) (int, error) {
+	return sw.frame.Write(p)
+}
+
+// endFrame writes the buffered payload as a single frame.
+func (sw *snapshotWriter) endFrame() error {
+	if sw.frame.Len() == 0 {
+		return nil
+	}
+	if sw.frame.Len() > maxFrameSize {
+		return fmt.Errorf("otter: too large entry: %d bytes", sw.frame.Len())
+	}
+
+	write := sw.write
+	if sw.sharded {
+		if err := sw.startShard(); err != nil {
+			return err
+		}
+		write = sw.writeShard
+		sw.shardCount++
+	}
+	if err := write(binary.AppendUvarint(sw.buf[:0], uint64(sw.frame.Len()))); err != nil {
+		return err
+	}
+	err := write(sw.frame.Bytes())
+	sw.frame.Reset()
+	sw.count++
+	if err != nil {
+		return err
+	}
+	if sw.sharded && sw.shardCount >= sw.entriesPerShard {
+		return sw.endShard()
+	}
+	return nil
+}
+
+// shardEnded reports whether the next frame starts a new shard. Every shard is decoded independently,
+// so it must be encoded by a new encoder.
+func (sw *snapshotWriter) shardEnded() bool {
+	return sw.sharded && sw.shardOut == nil
+}
+
+func (sw *snapshotWriter) startShard() error {
+	if sw.shardOut != nil {
+		return nil
+	}
+
+	sw.shard.Reset()
+	sw.shardOut = &sw.shard
+	if sw.shardCompressor != nil {
+		cw, err := sw.shardCompressor.NewWriter(&sw.shard)
+		if err != nil {
+			return fmt.Errorf("otter: create %s writer: %w", sw.shardCompressor.Name(), err)
+		}
+		sw.shardCW = cw
+		sw.shardOut = cw
+	}
+	return nil
+}
+
+func (sw *snapshotWriter) writeShard(b []byte) error {
+	_, err := sw.shardOut.Write(b)
+	return err
+}
+
+// endShard writes the current shard, if it contains any frames.
+func (sw *snapshotWriter) endShard() error {
+	if !sw.sharded || sw.shardOut == nil {
+		return nil
+	}
+
+	if err := sw.writeShard(binary.AppendUvarint(sw.buf[:0], 0)); err != nil {
+		return err
+	}
+	if sw.shardCW != nil {
+		if err := sw.shardCW.Close(); err != nil {
+			return err
+		}
+	}
+
+	b := binary.AppendUvarint(sw.buf[:0], sw.shardCount)
+	b = binary.AppendUvarint(b, uint64(sw.shard.Len()))
+	if err := sw.write(b); err != nil {
+		return err
+	}
+	err := sw.write(sw.shard.Bytes())
+	sw.shardOut = nil
+	sw.shardCW = nil
+	sw.shardCount = 0
+	return err
+}
+
+// writeTrailer terminates the body, writes the policy section (if ps is not nil)
+// and the trailer and flushes the data.
+func (sw *snapshotWriter) writeTrailer(ps *policyState) error {
+	if err := sw.endShard(); err != nil {
+		return err
+	}
+	if err := sw.write(binary.AppendUvarint(sw.buf[:0], 0)); err != nil {
+		return err
+	}
+	if ps != nil {
+		if uint64(len(ps.nodes)) != sw.count {
+			return fmt.Errorf("otter: expected %d policy states, but got %d", sw.count, len(ps.nodes))
+		}
+		b := make([]byte, 0, 16+2*len(ps.nodes))
+		b = binary.BigEndian.AppendUint64(b, ps.windowMaximum)
+		b = binary.BigEndian.AppendUint64(b, ps.mainProtectedMaximum)
+		for _, state := range ps.nodes {
+			b = append(b, state.frequency, state.queueType)
+		}
+		if err := sw.write(b); err != nil {
+			return err
+		}
+	}
+	if err := sw.write(binary.BigEndian.AppendUint64(sw.buf[:0], sw.count)); err != nil {
+		return err
+	}
+	if _, err := sw.out.Write(binary.BigEndian.AppendUint32(sw.buf[:0], sw.crc.Sum32())); err != nil {
+		return err
+	}
+	if sw.cw != nil {
+		if err := sw.cw.Close(); err != nil {
+			return err
+		}
+	}
+	return sw.w.Flush()
+}
+
+// snapshotReader reads the snapshot format and verifies its checksum.
+//
+// snapshotReader exposes the concatenation of the frame payloads as an [io.Reader],
+// which returns [io.EOF] after the last frame.
+type snapshotReader struct {
+	r         *bufio.Reader
+	cr        io.ReadCloser
+	crc       hash.Hash32
+	buf       [32]byte
+	remaining uint64
+	done      bool
+}
+
+func newSnapshotReader(r io.Reader) *snapshotReader {
+	return &snapshotReader{
+		r:   bufio.NewReader(r),
+		crc: crc32.New(crcTable),
+	}
+}
+
+func (sr *snapshotReader) readFull(b []byte) error {
+	if _, err := io.ReadFull(sr.r, b); err != nil {
+		return corrupted(err)
+	}
+	//nolint:errcheck // hash.Hash never returns an error
+	_, _ = sr.crc.Write(b)
+	return nil
+}
+
+func (sr *snapshotReader) readUvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(hashByteReader{sr})
+	if err != nil {
+		return 0, corrupted(err)
+	}
+	return v, nil
+}
+
+type hashByteReader struct {
+	sr *snapshotReader
+}
+
+func (hr hashByteReader) ReadByte() (byte, error) {
+	b, err := hr.sr.r.ReadByte()
+	if err == nil {
+		//nolint:errcheck // hash.Hash never returns an error
+		_, _ = hr.sr.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+// isLegacy reports whether the data starts with something other than the magic,
+// which means that it's a snapshot of the previous format without a header.
+func (sr *snapshotReader) isLegacy() bool {
+	b, _ := sr.r.Peek(len(snapshotMagic))
+	return len(b) > 0 && string(b) != snapshotMagic[:len(b)]
+}
+
+func (sr *snapshotReader) readHeader() (snapshotHeader, error) {
+	b := sr.buf[:len(snapshotMagic)+2+2+8+8]
+	if err := sr.readFull(b); err != nil {
+		if errors.Is(err, ErrCorruptedSnapshot) {
+			return snapshotHeader{}, fmt.Errorf("%w: too short header", ErrInvalidSnapshot)
+		}
+		return snapshotHeader{}, err
+	}
+	if string(b[:len(snapshotMagic)]) != snapshotMagic {
+		return snapshotHeader{}, fmt.Errorf("%w: bad magic", ErrInvalidSnapshot)
+	}
+	b = b[len(snapshotMagic):]
+
+	h := snapshotHeader{
+		version:     binary.BigEndian.Uint16(b),
+		flags:       binary.BigEndian.Uint16(b[2:]),
+		fingerprint: binary.BigEndian.Uint64(b[4:]),
+		maximum:     binary.BigEndian.Uint64(b[12:]),
+	}
+	if h.version != snapshotVersion {
+		return h, fmt.Errorf("%w: %d", ErrUnsupportedSnapshotVersion, h.version)
+	}
+	if h.flags&^knownSnapshotFlags != 0 {
+		return h, fmt.Errorf("%w: unknown flags %#x", ErrUnsupportedSnapshotVersion, h.flags)
+	}
+
+	b = sr.buf[:1]
+	if err := sr.readFull(b); err != nil {
+		return h, err
+	}
+	name := make([]byte, b[0])
+	if err := sr.readFull(name); err != nil {
+		return h, err
+	}
+	h.compression = string(name)
+	return h, nil
+}
+
+// decompress makes the rest of the snapshot to be read through the compressor.
+func (sr *snapshotReader) decompress(compressor Compressor) error {
+	cr, err := compressor.NewReader(sr.r)
+	if err != nil {
+		return fmt.Errorf("%w: create %s reader: %w", ErrCorruptedSnapshot, compressor.Name(), err)
+	}
+	sr.cr = cr
+	sr.r = bufio.NewReader(decompressedReader{r: cr})
+	return nil
+}
+
+// decompressedReader reports the errors of the decompressor as [ErrCorruptedSnapshot].
+type decompressedReader struct {
+	r io.Reader
+}
+
+func (dr decompressedReader) Read(p []byte) (int, error) {
+	n, err := dr.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, ErrCorruptedSnapshot) {
+		err = fmt.Errorf("%w: %w", ErrCorruptedSnapshot, err)
+	}
+	return n, err
+}
+
+func (sr *snapshotReader) close() error {
+	if sr.cr == nil {
+		return nil
+	}
+	return sr.cr.Close()
+}
+
+// Read reads the frame payloads.
+func (sr *snapshotReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := sr.nextFrame(); err != nil {
+		return 0, err
+	}
+
+	p = p[:min(uint64(len(p)), sr.remaining)]
+	n, err := sr.r.Read(p)
+	//nolint:errcheck // hash.Hash never returns an error
+	_, _ = sr.crc.Write(p[:n])
+	//nolint:gosec // there is no overflow
+	sr.remaining -= uint64(n)
+	if err != nil {
+		return n, corrupted(err)
+	}
+	return n, nil
+}
+
+// ReadByte reads a single byte of the frame payloads.
+func (sr *snapshotReader) ReadByte() (byte, error) {
+	if err := sr.nextFrame(); err != nil {
+		return 0, err
+	}
+
+	b, err := sr.r.ReadByte()
+	if err != nil {
+		return 0, corrupted(err)
+	}
+	//nolint:errcheck // hash.Hash never returns an error
+	_, _ = sr.crc.Write([]byte{b})
+	sr.remaining--
+	return b, nil
+}
+
+func (sr *snapshotReader) nextFrame() error {
+	for sr.remaining == 0 {
+		if sr.done {
+			return io.EOF
+		}
+
+		length, err := sr.readUvarint()
+		if err != nil {
+			return err
+		}
+		if length == 0 {
+			sr.done = true
+			return io.EOF
+		}
+		if length > maxFrameSize {
+			return fmt.Errorf("%w: too large frame", ErrCorruptedSnapshot)
+		}
+		sr.remaining = length
+	}
+	return nil
+}
+
+// readShards reads the shards of a sharded snapshot.
+func (sr *snapshotReader) readShards() ([]snapshotShard, error) {
+	var shards []snapshotShard
+	for {
+		count, err := sr.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			sr.done = true
+			return shards, nil
+		}
+
+		length, err := sr.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		// the data is buffered gradually, so a corrupted length doesn't cause a huge allocation.
+		var data bytes.Buffer
+		if length > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: too large shard", ErrCorruptedSnapshot)
+		}
+		if _, err := io.CopyN(io.MultiWriter(&data, sr.crc), sr.r, int64(length)); err != nil {
+			return nil, corrupted(err)
+		}
+		shards = append(shards, snapshotShard{
+			count: count,
+			data:  data.Bytes(),
+		})
+	}
+}
+
+// snapshotShard is a shard of a sharded snapshot.
+type snapshotShard struct {
+	count uint64
+	data  []byte
+}
+
+// reader returns the reader of the shard frame payloads.
+func (s snapshotShard) reader(compressor Compressor) (*snapshotReader, error) {
+	sr := newSnapshotReader(bytes.NewReader(s.data))
+	if compressor != nil {
+		if err := sr.decompress(compressor); err != nil {
+			return nil, err
+		}
+	}
+	return sr, nil
+}
+
+// readTrailer reads the policy section (if ps is not nil) and the trailer and verifies
+// that the body contained count entries and the checksum matches.
+func (sr *snapshotReader) readTrailer(count uint64, ps *policyState) error {
+	if !sr.done || sr.remaining != 0 {
+		return fmt.Errorf("%w: unexpected data after the last entry", ErrCorruptedSnapshot)
+	}
+
+	if ps != nil {
+		b := sr.buf[:16]
+		if err := sr.readFull(b); err != nil {
+			return err
+		}
+		ps.windowMaximum = binary.BigEndian.Uint64(b)
+		ps.mainProtectedMaximum = binary.BigEndian.Uint64(b[8:])
+
+		b = make([]byte, 2*count)
+		if err := sr.readFull(b); err != nil {
+			return err
+		}
+		ps.nodes = make([]nodeState, count)
+		for i := range ps.nodes {
+			ps.nodes[i] = nodeState{
+				frequency: b[2*i],
+				queueType: b[2*i+1],
+			}
+		}
+	}
+
+	b := sr.buf[:8]
+	if err := sr.readFull(b); err != nil {
+		return err
+	}
+	savedCount := binary.BigEndian.Uint64(b)
+	checksum := sr.crc.Sum32()
+
+	b = sr.buf[:4]
+	if _, err := io.ReadFull(sr.r, b); err != nil {
+		return corrupted(err)
+	}
+	if binary.BigEndian.Uint32(b) != checksum {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorruptedSnapshot)
+	}
+	if savedCount != count {
+		return fmt.Errorf("%w: expected %d entries, but got %d", ErrCorruptedSnapshot, savedCount, count)
+	}
+	return nil
+}
+
+func corrupted(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: unexpected end of data", ErrCorruptedSnapshot)
+	}
+	return err
+}
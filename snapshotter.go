@@ -0,0 +1,140 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Snapshotter periodically saves a [Cache] to a file using [SaveCacheToFile].
+//
+// The interval is measured using the cache's Clock. Failed snapshots are logged using the cache's Logger,
+// and the next snapshot is attempted after the interval.
+//
+// The Snapshotter must be closed with Close, which stops the periodic snapshots and takes the final one.
+// The Cache is not garbage collected until its Snapshotter is closed.
+type Snapshotter[K comparable, V any] struct {
+	cache       *Cache[K, V]
+	filePath    string
+	opts        *SaveOptions[K, V]
+	clock       timeSource
+	saveMutex   sync.Mutex
+	stateMutex  sync.Mutex
+	lastSuccess int64
+	lastErr     error
+	closeOnce   sync.Once
+	closeErr    error
+	done        chan struct{}
+	stopped     chan struct{}
+}
+
+// NewSnapshotter creates a [Snapshotter] that saves the cache to filePath every interval
+// and starts it.
+//
+// If several SaveOptions are specified, only the last non-nil one is used.
+func NewSnapshotter[K comparable, V any](
+	c *Cache[K, V],
+	filePath string,
+	interval time.Duration,
+	opts ...*SaveOptions[K, V],
+) (*Snapshotter[K, V], error) {
+	if interval <= 0 {
+		return nil, errors.New("otter: snapshot interval should be positive")
+	}
+
+	clock := c.cache.clock
+	clock.Init()
+	s := &Snapshotter[K, V]{
+		cache:    c,
+		filePath: filePath,
+		opts:     lastOptions(opts),
+		clock:    clock,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go s.run(interval)
+	return s, nil
+}
+
+func (s *Snapshotter[K, V]) run(interval time.Duration) {
+	defer close(s.stopped)
+
+	tick := s.clock.Tick(interval)
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-tick:
+			if err := s.Snapshot(); err != nil {
+				s.cache.cache.logger.Error(context.Background(), "Failed to save cache snapshot", err)
+			}
+		}
+	}
+}
+
+// Snapshot saves the cache immediately.
+//
+// Snapshot is safe for concurrent use, but the snapshots are taken one at a time.
+func (s *Snapshotter[K, V]) Snapshot() error {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+
+	err := SaveCacheToFile(s.cache, s.filePath, s.opts)
+
+	s.stateMutex.Lock()
+	if err == nil {
+		s.lastSuccess = s.clock.NowNano()
+	}
+	s.lastErr = err
+	s.stateMutex.Unlock()
+
+	return err
+}
+
+// LastSuccess returns the time when the last successful snapshot was taken
+// or the zero time if there were no successful snapshots.
+func (s *Snapshotter[K, V]) LastSuccess() time.Time {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+
+	if s.lastSuccess == noTime {
+		return time.Time{}
+	}
+	return time.Unix(0, s.lastSuccess)
+}
+
+// LastError returns the error of the last snapshot or nil if it was successful.
+func (s *Snapshotter[K, V]) LastError() error {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+
+	return s.lastErr
+}
+
+// Close stops the periodic snapshots and takes the final one.
+//
+// Close returns the error of the final snapshot. Subsequent calls return the same error
+// without taking a snapshot.
+func (s *Snapshotter[K, V]) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		<-s.stopped
+		s.closeErr = s.Snapshot()
+	})
+	return s.closeErr
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type manualClock struct {
+	now    atomic.Int64
+	ticker chan time.Time
+}
+
+func newManualClock() *manualClock {
+	mc := &manualClock{
+		ticker: make(chan time.Time),
+	}
+	mc.now.Store(time.Now().UnixNano())
+	return mc
+}
+
+func (mc *manualClock) NowNano() int64 {
+	return mc.now.Load()
+}
+
+func (mc *manualClock) Tick(duration time.Duration) <-chan time.Time {
+	return mc.ticker
+}
+
+func (mc *manualClock) advance(d time.Duration) {
+	mc.now.Add(int64(d))
+}
+
+func (mc *manualClock) tick() {
+	mc.ticker <- time.Unix(0, mc.now.Load())
+}
+
+func TestSnapshotter(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "cache.snap")
+	mc := newManualClock()
+	c := Must(&Options[int, int]{
+		Clock:  mc,
+		Logger: &NoopLogger{},
+	})
+
+	_, err := NewSnapshotter(c, filePath, 0)
+	require.Error(t, err)
+
+	s, err := NewSnapshotter(c, filePath, time.Minute, &SaveOptions[int, int]{
+		Generations: 1,
+	})
+	require.NoError(t, err)
+	require.True(t, s.LastSuccess().IsZero())
+	require.NoError(t, s.LastError())
+
+	c.Set(1, 1)
+	mc.advance(time.Minute)
+	mc.tick()
+	// the ticker is unbuffered, so the next tick is received only after the previous snapshot.
+	mc.tick()
+	require.Equal(t, time.Unix(0, mc.NowNano()), s.LastSuccess())
+	require.NoError(t, s.LastError())
+
+	loaded := Must(&Options[int, int]{})
+	require.NoError(t, LoadCacheFromFile(loaded, filePath))
+	require.Equal(t, 1, loaded.EstimatedSize())
+
+	c.Set(2, 2)
+	mc.advance(time.Minute)
+	require.NoError(t, s.Close())
+	require.NoError(t, s.Close())
+	require.Equal(t, time.Unix(0, mc.NowNano()), s.LastSuccess())
+
+	loaded = Must(&Options[int, int]{})
+	require.NoError(t, LoadCacheFromFile(loaded, filePath))
+	require.Equal(t, 2, loaded.EstimatedSize())
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		filePath := filepath.Join(dir, "file")
+		require.NoError(t, os.WriteFile(filePath, nil, 0o600))
+
+		s, err := NewSnapshotter(c, filepath.Join(filePath, "cache.snap"), time.Minute)
+		require.NoError(t, err)
+		require.Error(t, s.Close())
+		require.Error(t, s.LastError())
+		require.True(t, s.LastSuccess().IsZero())
+	})
+}
@@ -25,15 +25,27 @@ import (
 
 // Counter is a goroutine-safe [Recorder] implementation for use by otter.Cache.
 type Counter struct {
-	hits           *xsync.Adder
-	misses         *xsync.Adder
-	_              [xruntime.CacheLineSize - 16]byte
-	evictions      atomic.Uint64
-	evictionWeight atomic.Uint64
-	_              [xruntime.CacheLineSize - 16]byte
-	loadSuccesses  atomic.Uint64
-	loadFailures   atomic.Uint64
-	totalLoadTime  atomic.Uint64
+	hits             *xsync.Adder
+	misses           *xsync.Adder
+	_                [xruntime.CacheLineSize - 16]byte
+	evictions        atomic.Uint64
+	evictionWeight   atomic.Uint64
+	_                [xruntime.CacheLineSize - 16]byte
+	overflows        atomic.Uint64
+	expirations      atomic.Uint64
+	invalidations    atomic.Uint64
+	replacements     atomic.Uint64
+	_                [xruntime.CacheLineSize - 32]byte
+	loadSuccesses    atomic.Uint64
+	loadFailures     atomic.Uint64
+	totalLoadTime    atomic.Uint64
+	_                [xruntime.CacheLineSize - 24]byte
+	refreshSuccesses atomic.Uint64
+	refreshFailures  atomic.Uint64
+	totalRefreshTime atomic.Uint64
+	_                [xruntime.CacheLineSize - 24]byte
+	staleServes      atomic.Uint64
+	coalescedLoads   atomic.Uint64
 }
 
 // NewCounter constructs a [Counter] instance with all counts initialized to zero.
@@ -54,14 +66,27 @@ func (c *Counter) Snapshot() Stats {
 	if totalLoadTime > uint64(math.MaxInt64) {
 		totalLoadTime = uint64(math.MaxInt64)
 	}
+	totalRefreshTime := c.totalRefreshTime.Load()
+	if totalRefreshTime > uint64(math.MaxInt64) {
+		totalRefreshTime = uint64(math.MaxInt64)
+	}
 	return Stats{
-		Hits:           c.hits.Value(),
-		Misses:         c.misses.Value(),
-		Evictions:      c.evictions.Load(),
-		EvictionWeight: c.evictionWeight.Load(),
-		LoadSuccesses:  c.loadSuccesses.Load(),
-		LoadFailures:   c.loadFailures.Load(),
-		TotalLoadTime:  time.Duration(totalLoadTime),
+		Hits:                c.hits.Value(),
+		Misses:              c.misses.Value(),
+		Evictions:           c.evictions.Load(),
+		EvictionWeight:      c.evictionWeight.Load(),
+		OverflowEvictions:   c.overflows.Load(),
+		ExpirationEvictions: c.expirations.Load(),
+		Invalidations:       c.invalidations.Load(),
+		Replacements:        c.replacements.Load(),
+		LoadSuccesses:       c.loadSuccesses.Load(),
+		LoadFailures:        c.loadFailures.Load(),
+		TotalLoadTime:       time.Duration(totalLoadTime),
+		RefreshSuccesses:    c.refreshSuccesses.Load(),
+		RefreshFailures:     c.refreshFailures.Load(),
+		TotalRefreshTime:    time.Duration(totalRefreshTime),
+		StaleServes:         c.staleServes.Load(),
+		CoalescedLoads:      c.coalescedLoads.Load(),
 	}
 }
 
@@ -85,6 +110,24 @@ func (c *Counter) RecordEviction(weight uint32) {
 	c.evictionWeight.Add(uint64(weight))
 }
 
+// RecordDeletion records the deletion of an entry from the cache for the specified cause, including
+// manual deletions and replacements. The evictions are also counted by RecordEviction.
+func (c *Counter) RecordDeletion(cause DeletionCause, weight uint32) {
+	switch cause {
+	case CauseInvalidation:
+		c.invalidations.Add(1)
+	case CauseReplacement:
+		c.replacements.Add(1)
+	case CauseOverflow:
+		c.overflows.Add(1)
+	case CauseExpiration:
+		c.expirations.Add(1)
+	}
+	if cause.IsEviction() {
+		c.RecordEviction(weight)
+	}
+}
+
 // RecordLoadSuccess records the successful load of a new entry. This method should be called when a cache request
 // causes an entry to be loaded and the loading completes successfully (either no error or otter.ErrNotFound).
 func (c *Counter) RecordLoadSuccess(loadTime time.Duration) {
@@ -100,3 +143,32 @@ func (c *Counter) RecordLoadFailure(loadTime time.Duration) {
 	//nolint:gosec // there is no overflow
 	c.totalLoadTime.Add(uint64(loadTime))
 }
+
+// RecordRefreshSuccess records the successful refresh of an entry. This method should be called when
+// the refreshing completes successfully (either no error or otter.ErrNotFound).
+func (c *Counter) RecordRefreshSuccess(loadTime time.Duration) {
+	c.refreshSuccesses.Add(1)
+	//nolint:gosec // there is no overflow
+	c.totalRefreshTime.Add(uint64(loadTime))
+}
+
+// RecordRefreshFailure records the failed refresh of an entry. This method should be called when
+// the refreshing function returns an error that is not otter.ErrNotFound, so the stale value is kept.
+func (c *Counter) RecordRefreshFailure(loadTime time.Duration) {
+	c.refreshFailures.Add(1)
+	//nolint:gosec // there is no overflow
+	c.totalRefreshTime.Add(uint64(loadTime))
+}
+
+// RecordStaleServes records that the cache lookup methods returned values that are eligible for refresh.
+func (c *Counter) RecordStaleServes(count int) {
+	//nolint:gosec // there is no overflow
+	c.staleServes.Add(uint64(count))
+}
+
+// RecordCoalescedLoads records the loads and refreshes that were not started because another load or
+// refresh of the same key was already in progress.
+func (c *Counter) RecordCoalescedLoads(count int) {
+	//nolint:gosec // there is no overflow
+	c.coalescedLoads.Add(uint64(count))
+}
@@ -47,14 +47,63 @@ func TestCounter_Basic(t *testing.T) {
 		}
 	})
 
+	t.Run("deletions", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewCounter()
+		c.RecordDeletion(CauseInvalidation, 1)
+		c.RecordDeletion(CauseReplacement, 2)
+		c.RecordDeletion(CauseOverflow, 3)
+		c.RecordDeletion(CauseOverflow, 4)
+		c.RecordDeletion(CauseExpiration, 5)
+
+		expected := Stats{
+			Evictions:           3,
+			EvictionWeight:      12,
+			OverflowEvictions:   2,
+			ExpirationEvictions: 1,
+			Invalidations:       1,
+			Replacements:        1,
+		}
+		if got := c.Snapshot(); got != expected {
+			t.Fatalf("got = %+v, expected = %+v", got, expected)
+		}
+	})
+
+	t.Run("refreshes", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewCounter()
+		c.RecordRefreshSuccess(2)
+		c.RecordRefreshFailure(3)
+		c.RecordStaleServes(4)
+		c.RecordCoalescedLoads(5)
+
+		expected := Stats{
+			RefreshSuccesses: 1,
+			RefreshFailures:  1,
+			TotalRefreshTime: 5,
+			StaleServes:      4,
+			CoalescedLoads:   5,
+		}
+		if got := c.Snapshot(); got != expected {
+			t.Fatalf("got = %+v, expected = %+v", got, expected)
+		}
+		if got := c.Snapshot().RefreshFailureRatio(); got != 0.5 {
+			t.Fatalf("refreshFailureRatio should be 0.5, but got %.2f", got)
+		}
+	})
+
 	t.Run("overflow", func(t *testing.T) {
 		t.Parallel()
 
 		c := NewCounter()
 		c.totalLoadTime.Add(math.MaxUint64)
+		c.totalRefreshTime.Add(math.MaxUint64)
 
 		expected := Stats{
-			TotalLoadTime: math.MaxInt64,
+			TotalLoadTime:    math.MaxInt64,
+			TotalRefreshTime: math.MaxInt64,
 		}
 
 		if got := c.Snapshot(); got != expected {
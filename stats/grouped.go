@@ -0,0 +1,70 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import "sync"
+
+// GroupRecorder is a [Recorder] that also keeps statistics per group of keys (for example, per tenant).
+//
+// otter.Cache records the hits, misses, loads and deletions of a key to the recorder of the key's group,
+// which is determined by otter.Options.StatsClassifier, in addition to recording them to the GroupRecorder itself.
+// The recorder of a group receives refreshes and deletions by cause if it implements [RefreshRecorder] and
+// [DeletionRecorder] respectively.
+type GroupRecorder interface {
+	Recorder
+	// Group returns the recorder of the group with the specified name. It's called for every recorded
+	// event, so it should be fast.
+	Group(name string) Recorder
+}
+
+// GroupedCounter is a goroutine-safe [GroupRecorder] implementation that keeps a [Counter] for the total
+// statistics and a [Counter] for every group.
+//
+// The counters of the groups are created on the first use and are never deleted, so the number of groups
+// should be small.
+type GroupedCounter struct {
+	*Counter
+	groups sync.Map
+}
+
+// NewGroupedCounter constructs a [GroupedCounter] instance with all counts initialized to zero.
+func NewGroupedCounter() *GroupedCounter {
+	return &GroupedCounter{
+		Counter: NewCounter(),
+	}
+}
+
+// Group returns the recorder of the group with the specified name.
+func (c *GroupedCounter) Group(name string) Recorder {
+	if counter, ok := c.groups.Load(name); ok {
+		//nolint:errcheck // the map contains only counters
+		return counter.(*Counter)
+	}
+	counter, _ := c.groups.LoadOrStore(name, NewCounter())
+	//nolint:errcheck // the map contains only counters
+	return counter.(*Counter)
+}
+
+// Groups returns a snapshot of the statistics of every group. Note that this may be an inconsistent view,
+// as it may be interleaved with update operations.
+func (c *GroupedCounter) Groups() map[string]Stats {
+	result := make(map[string]Stats)
+	c.groups.Range(func(key, value any) bool {
+		//nolint:errcheck // the map contains only counters
+		result[key.(string)] = value.(*Counter).Snapshot()
+		return true
+	})
+	return result
+}
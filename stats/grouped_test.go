@@ -0,0 +1,58 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestGroupedCounter(t *testing.T) {
+	t.Parallel()
+
+	c := NewGroupedCounter()
+	if got := c.Groups(); len(got) != 0 {
+		t.Fatalf("groups should be empty, but got %+v", got)
+	}
+
+	goroutines := 50
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+
+			group := c.Group(strconv.Itoa(i % 2))
+			group.RecordHits(1)
+			group.RecordEviction(10)
+		}()
+	}
+
+	wg.Wait()
+
+	expected := Stats{
+		Hits:           25,
+		Evictions:      25,
+		EvictionWeight: 250,
+	}
+	groups := c.Groups()
+	if len(groups) != 2 || groups["0"] != expected || groups["1"] != expected {
+		t.Fatalf("got = %+v, expected = %+v for every group", groups, expected)
+	}
+	if got := c.Snapshot(); got != (Stats{}) {
+		t.Fatalf("the groups should not be recorded to the total stats, but got %+v", got)
+	}
+}
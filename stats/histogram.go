@@ -0,0 +1,185 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"github.com/maypok86/otter/v2/internal/xsync"
+)
+
+const (
+	// subBucketBits is the number of bits used to split every power of two into linear sub-buckets.
+	// 16 sub-buckets give a relative error of at most 6.25%.
+	subBucketBits  = 4
+	subBucketCount = 1 << subBucketBits
+	// bucketCount is enough to cover all non-negative int64 values.
+	bucketCount = (64-subBucketBits)*subBucketCount + subBucketCount
+)
+
+// Latency is a summary of the distribution of durations.
+type Latency struct {
+	// Count is the number of recorded durations.
+	Count uint64
+	// P50 is the median duration.
+	P50 time.Duration
+	// P90 is the 90th percentile of durations.
+	P90 time.Duration
+	// P99 is the 99th percentile of durations.
+	P99 time.Duration
+	// Max is the maximum duration.
+	Max time.Duration
+}
+
+// LoadLatency contains the distributions of load times.
+type LoadLatency struct {
+	// Success is the distribution of the successful load times.
+	Success Latency
+	// Failure is the distribution of the failed load times.
+	Failure Latency
+}
+
+// HistogramCounter is a goroutine-safe [Recorder] implementation that records the same statistics as [Counter]
+// and additionally keeps the distributions of load times to provide their percentiles.
+//
+// The load times are recorded into fixed log-linear buckets, so the percentiles have a relative error of at
+// most 6.25%.
+type HistogramCounter struct {
+	*Counter
+	loadSuccesses histogram
+	loadFailures  histogram
+}
+
+// NewHistogramCounter constructs a [HistogramCounter] instance with all counts initialized to zero.
+func NewHistogramCounter() *HistogramCounter {
+	return &HistogramCounter{
+		Counter: NewCounter(),
+	}
+}
+
+// RecordLoadSuccess records the successful load of a new entry. This method should be called when a cache request
+// causes an entry to be loaded and the loading completes successfully (either no error or otter.ErrNotFound).
+func (c *HistogramCounter) RecordLoadSuccess(loadTime time.Duration) {
+	c.Counter.RecordLoadSuccess(loadTime)
+	c.loadSuccesses.record(loadTime)
+}
+
+// RecordLoadFailure records the failed load of a new entry. This method should be called when a cache request
+// causes an entry to be loaded, but the loading function returns an error that is not otter.ErrNotFound.
+func (c *HistogramCounter) RecordLoadFailure(loadTime time.Duration) {
+	c.Counter.RecordLoadFailure(loadTime)
+	c.loadFailures.record(loadTime)
+}
+
+// LoadLatency returns the distributions of the successful and failed load times. Note that this may be
+// an inconsistent view, as it may be interleaved with update operations.
+func (c *HistogramCounter) LoadLatency() LoadLatency {
+	return LoadLatency{
+		Success: c.loadSuccesses.latency(),
+		Failure: c.loadFailures.latency(),
+	}
+}
+
+// histogram is a lock-free histogram of durations with fixed log-linear buckets.
+//
+// Durations less than subBucketCount nanoseconds are recorded exactly. Every other power of two
+// is split into subBucketCount buckets of the same width.
+//
+// The counts are striped like the ones of Counter to avoid contention. The adder of a bucket is allocated
+// on the first use, since the durations usually fall into a few buckets.
+type histogram struct {
+	buckets [bucketCount]atomic.Pointer[xsync.Adder]
+	max     atomic.Int64
+}
+
+func (h *histogram) bucket(i int) *xsync.Adder {
+	if a := h.buckets[i].Load(); a != nil {
+		return a
+	}
+	a := xsync.NewAdder()
+	if h.buckets[i].CompareAndSwap(nil, a) {
+		return a
+	}
+	return h.buckets[i].Load()
+}
+
+func (h *histogram) record(d time.Duration) {
+	v := max(int64(d), 0)
+	h.bucket(bucketIndex(uint64(v))).Add(1)
+	for {
+		current := h.max.Load()
+		if v <= current || h.max.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+func (h *histogram) latency() Latency {
+	var counts [bucketCount]uint64
+	var total uint64
+	for i := range h.buckets {
+		if a := h.buckets[i].Load(); a != nil {
+			counts[i] = a.Value()
+			total += counts[i]
+		}
+	}
+	if total == 0 {
+		return Latency{}
+	}
+
+	maximum := h.max.Load()
+	quantile := func(q float64) time.Duration {
+		rank := uint64(math.Ceil(q * float64(total)))
+		var cumulative uint64
+		for i, count := range counts {
+			cumulative += count
+			if cumulative >= rank {
+				//nolint:gosec // there is no overflow
+				return time.Duration(min(bucketUpperBound(i), uint64(maximum)))
+			}
+		}
+		return time.Duration(maximum)
+	}
+	return Latency{
+		Count: total,
+		P50:   quantile(0.5),
+		P90:   quantile(0.9),
+		P99:   quantile(0.99),
+		Max:   time.Duration(maximum),
+	}
+}
+
+func bucketIndex(v uint64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	exp := bits.Len64(v) - 1
+	sub := (v >> (exp - subBucketBits)) & (subBucketCount - 1)
+	return (exp-subBucketBits+1)*subBucketCount + int(sub)
+}
+
+// bucketUpperBound returns the largest value that is recorded into the bucket with the specified index.
+func bucketUpperBound(i int) uint64 {
+	if i < subBucketCount {
+		return uint64(i)
+	}
+	exp := i/subBucketCount + subBucketBits - 1
+	sub := uint64(i % subBucketCount)
+	width := uint64(1) << (exp - subBucketBits)
+	return (subBucketCount+sub)*width + width - 1
+}
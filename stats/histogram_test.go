@@ -0,0 +1,140 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistogram_Buckets(t *testing.T) {
+	t.Parallel()
+
+	values := []uint64{0, 1, 15, 16, 17, 31, 32, 33, 1000, 1 << 20, (1 << 20) + 1, math.MaxInt64, math.MaxUint64}
+	for v := uint64(0); v < 10_000; v++ {
+		values = append(values, v)
+	}
+	for _, v := range values {
+		i := bucketIndex(v)
+		if i < 0 || i >= bucketCount {
+			t.Fatalf("index of %d should be in [0, %d), but got %d", v, bucketCount, i)
+		}
+		if upper := bucketUpperBound(i); upper < v {
+			t.Fatalf("upper bound of the bucket %d should be at least %d, but got %d", i, v, upper)
+		}
+		if i > 0 {
+			if upper := bucketUpperBound(i - 1); upper >= v {
+				t.Fatalf("upper bound of the bucket %d should be less than %d, but got %d", i-1, v, upper)
+			}
+		}
+	}
+	if got := bucketUpperBound(bucketCount - 1); got != math.MaxUint64 {
+		t.Fatalf("upper bound of the last bucket should be %d, but got %d", uint64(math.MaxUint64), got)
+	}
+}
+
+func TestHistogramCounter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewHistogramCounter()
+		if got := c.LoadLatency(); got != (LoadLatency{}) {
+			t.Fatalf("got = %+v, expected = %+v", got, LoadLatency{})
+		}
+	})
+
+	t.Run("percentiles", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewHistogramCounter()
+		for i := 1; i <= 1000; i++ {
+			c.RecordLoadSuccess(time.Duration(i) * time.Millisecond)
+		}
+		c.RecordLoadFailure(-time.Second)
+		c.RecordLoadFailure(time.Hour)
+
+		latency := c.LoadLatency()
+		check := func(name string, got, expected time.Duration) {
+			t.Helper()
+
+			// the relative error is at most 1/subBucketCount.
+			if got < expected || float64(got-expected) > float64(expected)/subBucketCount {
+				t.Fatalf("%s should be about %s, but got %s", name, expected, got)
+			}
+		}
+		if latency.Success.Count != 1000 {
+			t.Fatalf("count should be 1000, but got %d", latency.Success.Count)
+		}
+		check("p50", latency.Success.P50, 500*time.Millisecond)
+		check("p90", latency.Success.P90, 900*time.Millisecond)
+		check("p99", latency.Success.P99, 990*time.Millisecond)
+		if latency.Success.Max != time.Second {
+			t.Fatalf("max should be %s, but got %s", time.Second, latency.Success.Max)
+		}
+
+		expected := Latency{
+			Count: 2,
+			P50:   0,
+			P90:   time.Hour,
+			P99:   time.Hour,
+			Max:   time.Hour,
+		}
+		if latency.Failure != expected {
+			t.Fatalf("got = %+v, expected = %+v", latency.Failure, expected)
+		}
+
+		s := c.Snapshot()
+		if s.LoadSuccesses != 1000 || s.LoadFailures != 2 {
+			t.Fatalf("loads should be recorded, but got %+v", s)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewHistogramCounter()
+
+		goroutines := 50
+		var wg sync.WaitGroup
+		wg.Add(goroutines)
+		for i := 0; i < goroutines; i++ {
+			go func() {
+				defer wg.Done()
+
+				for j := 1; j <= 100; j++ {
+					c.RecordLoadSuccess(time.Duration(j))
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		// the values from 64 to 127 are recorded into the buckets of width 4.
+		expected := Latency{
+			Count: 5000,
+			P50:   51,
+			P90:   91,
+			P99:   99,
+			Max:   100,
+		}
+		if got := c.LoadLatency().Success; got != expected {
+			t.Fatalf("got = %+v, expected = %+v", got, expected)
+		}
+	})
+}
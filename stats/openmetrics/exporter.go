@@ -0,0 +1,308 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package openmetrics exports the statistics of otter caches in the OpenMetrics text format,
+// which is understood by Prometheus and other monitoring systems.
+package openmetrics
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/maypok86/otter/v2/stats"
+)
+
+// ContentType is the content type of the OpenMetrics text format.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// ErrAlreadyRegistered is returned when a cache with the same name is already registered in the [Exporter].
+var ErrAlreadyRegistered = errors.New("openmetrics: cache with the same name is already registered")
+
+// Cache is the interface of a cache whose metrics are exported. *otter.Cache implements it.
+type Cache interface {
+	// Stats returns a current snapshot of the cache's cumulative statistics.
+	Stats() stats.Stats
+	// EstimatedSize returns the approximate number of entries in the cache.
+	EstimatedSize() int
+	// WeightedSize returns the approximate accumulated weight of entries in the cache.
+	WeightedSize() uint64
+	// GetMaximum returns the maximum total weighted or unweighted size of the cache.
+	GetMaximum() uint64
+	// IsWeighted returns whether the cache is bounded by a maximum size or maximum weight.
+	IsWeighted() bool
+}
+
+// Exporter renders the metrics of the registered caches in the OpenMetrics text format.
+//
+// Every metric has the "cache" label with the name of the cache. Exporter implements [http.Handler],
+// so it can be used as the handler of the metrics endpoint:
+//
+//	exporter := openmetrics.NewExporter()
+//	if err := exporter.Register("users", cache); err != nil {
+//		panic(err)
+//	}
+//	http.Handle("/metrics", exporter)
+//
+// Exporter is safe for concurrent use.
+type Exporter struct {
+	mutex  sync.RWMutex
+	caches map[string]Cache
+}
+
+// NewExporter returns a new [Exporter] without registered caches.
+func NewExporter() *Exporter {
+	return &Exporter{
+		caches: make(map[string]Cache),
+	}
+}
+
+// Register adds the cache to the exporter under the specified name.
+//
+// It returns [ErrAlreadyRegistered] if a cache with the same name is already registered.
+func (e *Exporter) Register(name string, cache Cache) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := e.caches[name]; ok {
+		return ErrAlreadyRegistered
+	}
+	e.caches[name] = cache
+	return nil
+}
+
+// Unregister removes the cache with the specified name from the exporter.
+func (e *Exporter) Unregister(name string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.caches, name)
+}
+
+// WriteTo writes the metrics of all registered caches to w in the OpenMetrics text format.
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	e.render(&buf)
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP implements [http.Handler] by writing the metrics of all registered caches.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	e.render(&buf)
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	if r.Method == http.MethodHead {
+		return
+	}
+	//nolint:errcheck // there is nothing to do with the error of the response
+	buf.WriteTo(w)
+}
+
+type snapshot struct {
+	name          string
+	stats         stats.Stats
+	estimatedSize int
+	weightedSize  uint64
+	maximum       uint64
+	isWeighted    bool
+}
+
+type metric struct {
+	name    string
+	typ     string
+	unit    string
+	help    string
+	samples func(s snapshot, sample func(labels string, value string))
+}
+
+func counter(name, help string, value func(s stats.Stats) uint64) metric {
+	return metric{
+		name: name,
+		typ:  "counter",
+		help: help,
+		samples: func(s snapshot, sample func(labels string, value string)) {
+			sample("", formatUint(value(s.stats)))
+		},
+	}
+}
+
+var metrics = []metric{
+	counter("otter_cache_hits", "The number of times cache lookup methods returned a cached value.",
+		func(s stats.Stats) uint64 { return s.Hits }),
+	counter("otter_cache_misses", "The number of times cache lookup methods did not find a cached value.",
+		func(s stats.Stats) uint64 { return s.Misses }),
+	counter("otter_cache_evictions", "The number of times an entry has been evicted.",
+		func(s stats.Stats) uint64 { return s.Evictions }),
+	counter("otter_cache_eviction_weight", "The sum of weights of evicted entries.",
+		func(s stats.Stats) uint64 { return s.EvictionWeight }),
+	{
+		name: "otter_cache_deletions",
+		typ:  "counter",
+		help: "The number of times an entry has been deleted by cause.",
+		samples: func(s snapshot, sample func(labels string, value string)) {
+			sample(`cause="overflow"`, formatUint(s.stats.OverflowEvictions))
+			sample(`cause="expiration"`, formatUint(s.stats.ExpirationEvictions))
+			sample(`cause="invalidation"`, formatUint(s.stats.Invalidations))
+			sample(`cause="replacement"`, formatUint(s.stats.Replacements))
+		},
+	},
+	counter("otter_cache_load_successes", "The number of times cache lookup methods have successfully loaded a new value.",
+		func(s stats.Stats) uint64 { return s.LoadSuccesses }),
+	counter("otter_cache_load_failures", "The number of times cache lookup methods failed to load a new value.",
+		func(s stats.Stats) uint64 { return s.LoadFailures }),
+	{
+		name: "otter_cache_load_duration_seconds",
+		typ:  "counter",
+		unit: "seconds",
+		help: "The time the cache has spent loading new values.",
+		samples: func(s snapshot, sample func(labels string, value string)) {
+			sample("", strconv.FormatFloat(s.stats.TotalLoadTime.Seconds(), 'g', -1, 64))
+		},
+	},
+	counter("otter_cache_refresh_successes", "The number of times the cache has successfully refreshed an entry.",
+		func(s stats.Stats) uint64 { return s.RefreshSuccesses }),
+	counter("otter_cache_refresh_failures", "The number of times the cache failed to refresh an entry.",
+		func(s stats.Stats) uint64 { return s.RefreshFailures }),
+	{
+		name: "otter_cache_refresh_duration_seconds",
+		typ:  "counter",
+		unit: "seconds",
+		help: "The time the cache has spent refreshing entries.",
+		samples: func(s snapshot, sample func(labels string, value string)) {
+			sample("", strconv.FormatFloat(s.stats.TotalRefreshTime.Seconds(), 'g', -1, 64))
+		},
+	},
+	counter("otter_cache_stale_serves", "The number of times cache lookup methods returned a value that is eligible for refresh.",
+		func(s stats.Stats) uint64 { return s.StaleServes }),
+	counter("otter_cache_coalesced_loads", "The number of loads and refreshes that joined a load or refresh in progress.",
+		func(s stats.Stats) uint64 { return s.CoalescedLoads }),
+	{
+		name: "otter_cache_estimated_size",
+		typ:  "gauge",
+		help: "The approximate number of entries in the cache.",
+		samples: func(s snapshot, sample func(labels string, value string)) {
+			sample("", strconv.Itoa(s.estimatedSize))
+		},
+	},
+	{
+		name: "otter_cache_weighted_size",
+		typ:  "gauge",
+		help: "The approximate accumulated weight of entries in the cache.",
+		samples: func(s snapshot, sample func(labels string, value string)) {
+			if s.isWeighted {
+				sample("", formatUint(s.weightedSize))
+			}
+		},
+	},
+	{
+		name: "otter_cache_maximum",
+		typ:  "gauge",
+		help: "The maximum total weighted or unweighted size of the cache.",
+		samples: func(s snapshot, sample func(labels string, value string)) {
+			// the unbounded caches have no maximum.
+			if s.maximum != math.MaxUint64 {
+				sample("", formatUint(s.maximum))
+			}
+		},
+	},
+}
+
+func (e *Exporter) render(buf *bytes.Buffer) {
+	e.mutex.RLock()
+	snapshots := make([]snapshot, 0, len(e.caches))
+	for name, c := range e.caches {
+		snapshots = append(snapshots, snapshot{
+			name:          name,
+			stats:         c.Stats(),
+			estimatedSize: c.EstimatedSize(),
+			weightedSize:  c.WeightedSize(),
+			maximum:       c.GetMaximum(),
+			isWeighted:    c.IsWeighted(),
+		})
+	}
+	e.mutex.RUnlock()
+
+	slices.SortFunc(snapshots, func(a, b snapshot) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	for _, m := range metrics {
+		buf.WriteString("# TYPE ")
+		buf.WriteString(m.name)
+		buf.WriteByte(' ')
+		buf.WriteString(m.typ)
+		buf.WriteByte('\n')
+		if m.unit != "" {
+			buf.WriteString("# UNIT ")
+			buf.WriteString(m.name)
+			buf.WriteByte(' ')
+			buf.WriteString(m.unit)
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("# HELP ")
+		buf.WriteString(m.name)
+		buf.WriteByte(' ')
+		buf.WriteString(m.help)
+		buf.WriteByte('\n')
+
+		sampleName := m.name
+		if m.typ == "counter" {
+			sampleName += "_total"
+		}
+		for _, s := range snapshots {
+			m.samples(s, func(labels string, value string) {
+				buf.WriteString(sampleName)
+				buf.WriteString(`{cache="`)
+				writeEscaped(buf, s.name)
+				buf.WriteByte('"')
+				if labels != "" {
+					buf.WriteByte(',')
+					buf.WriteString(labels)
+				}
+				buf.WriteString("} ")
+				buf.WriteString(value)
+				buf.WriteByte('\n')
+			})
+		}
+	}
+	buf.WriteString("# EOF\n")
+}
+
+// writeEscaped writes the label value escaping backslashes, double quotes and line feeds.
+func writeEscaped(buf *bytes.Buffer, value string) {
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\n':
+			buf.WriteString(`\n`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
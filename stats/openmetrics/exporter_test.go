@@ -0,0 +1,217 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openmetrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maypok86/otter/v2"
+	"github.com/maypok86/otter/v2/stats"
+)
+
+type fakeCache struct {
+	stats        stats.Stats
+	size         int
+	weightedSize uint64
+	maximum      uint64
+	isWeighted   bool
+}
+
+func (f *fakeCache) Stats() stats.Stats {
+	return f.stats
+}
+
+func (f *fakeCache) EstimatedSize() int {
+	return f.size
+}
+
+func (f *fakeCache) WeightedSize() uint64 {
+	return f.weightedSize
+}
+
+func (f *fakeCache) GetMaximum() uint64 {
+	return f.maximum
+}
+
+func (f *fakeCache) IsWeighted() bool {
+	return f.isWeighted
+}
+
+func TestExporter(t *testing.T) {
+	t.Parallel()
+
+	e := NewExporter()
+	require.NoError(t, e.Register("weighted", &fakeCache{
+		stats: stats.Stats{
+			Hits:                10,
+			Misses:              5,
+			Evictions:           3,
+			EvictionWeight:      30,
+			OverflowEvictions:   2,
+			ExpirationEvictions: 1,
+			Invalidations:       4,
+			Replacements:        6,
+			LoadSuccesses:       7,
+			LoadFailures:        1,
+			TotalLoadTime:       1500 * time.Millisecond,
+			RefreshSuccesses:    9,
+			RefreshFailures:     2,
+			TotalRefreshTime:    250 * time.Millisecond,
+			StaleServes:         12,
+			CoalescedLoads:      3,
+		},
+		size:         8,
+		weightedSize: 80,
+		maximum:      100,
+		isWeighted:   true,
+	}))
+	require.NoError(t, e.Register("un\"bounded\\\n", &fakeCache{
+		size:    1,
+		maximum: ^uint64(0),
+	}))
+	require.ErrorIs(t, e.Register("weighted", &fakeCache{}), ErrAlreadyRegistered)
+
+	expected := `# TYPE otter_cache_hits counter
+# HELP otter_cache_hits The number of times cache lookup methods returned a cached value.
+otter_cache_hits_total{cache="un\"bounded\\\n"} 0
+otter_cache_hits_total{cache="weighted"} 10
+# TYPE otter_cache_misses counter
+# HELP otter_cache_misses The number of times cache lookup methods did not find a cached value.
+otter_cache_misses_total{cache="un\"bounded\\\n"} 0
+otter_cache_misses_total{cache="weighted"} 5
+# TYPE otter_cache_evictions counter
+# HELP otter_cache_evictions The number of times an entry has been evicted.
+otter_cache_evictions_total{cache="un\"bounded\\\n"} 0
+otter_cache_evictions_total{cache="weighted"} 3
+# TYPE otter_cache_eviction_weight counter
+# HELP otter_cache_eviction_weight The sum of weights of evicted entries.
+otter_cache_eviction_weight_total{cache="un\"bounded\\\n"} 0
+otter_cache_eviction_weight_total{cache="weighted"} 30
+# TYPE otter_cache_deletions counter
+# HELP otter_cache_deletions The number of times an entry has been deleted by cause.
+otter_cache_deletions_total{cache="un\"bounded\\\n",cause="overflow"} 0
+otter_cache_deletions_total{cache="un\"bounded\\\n",cause="expiration"} 0
+otter_cache_deletions_total{cache="un\"bounded\\\n",cause="invalidation"} 0
+otter_cache_deletions_total{cache="un\"bounded\\\n",cause="replacement"} 0
+otter_cache_deletions_total{cache="weighted",cause="overflow"} 2
+otter_cache_deletions_total{cache="weighted",cause="expiration"} 1
+otter_cache_deletions_total{cache="weighted",cause="invalidation"} 4
+otter_cache_deletions_total{cache="weighted",cause="replacement"} 6
+# TYPE otter_cache_load_successes counter
+# HELP otter_cache_load_successes The number of times cache lookup methods have successfully loaded a new value.
+otter_cache_load_successes_total{cache="un\"bounded\\\n"} 0
+otter_cache_load_successes_total{cache="weighted"} 7
+# TYPE otter_cache_load_failures counter
+# HELP otter_cache_load_failures The number of times cache lookup methods failed to load a new value.
+otter_cache_load_failures_total{cache="un\"bounded\\\n"} 0
+otter_cache_load_failures_total{cache="weighted"} 1
+# TYPE otter_cache_load_duration_seconds counter
+# UNIT otter_cache_load_duration_seconds seconds
+# HELP otter_cache_load_duration_seconds The time the cache has spent loading new values.
+otter_cache_load_duration_seconds_total{cache="un\"bounded\\\n"} 0
+otter_cache_load_duration_seconds_total{cache="weighted"} 1.5
+# TYPE otter_cache_refresh_successes counter
+# HELP otter_cache_refresh_successes The number of times the cache has successfully refreshed an entry.
+otter_cache_refresh_successes_total{cache="un\"bounded\\\n"} 0
+otter_cache_refresh_successes_total{cache="weighted"} 9
+# TYPE otter_cache_refresh_failures counter
+# HELP otter_cache_refresh_failures The number of times the cache failed to refresh an entry.
+otter_cache_refresh_failures_total{cache="un\"bounded\\\n"} 0
+otter_cache_refresh_failures_total{cache="weighted"} 2
+# TYPE otter_cache_refresh_duration_seconds counter
+# UNIT otter_cache_refresh_duration_seconds seconds
+# HELP otter_cache_refresh_duration_seconds The time the cache has spent refreshing entries.
+otter_cache_refresh_duration_seconds_total{cache="un\"bounded\\\n"} 0
+otter_cache_refresh_duration_seconds_total{cache="weighted"} 0.25
+# TYPE otter_cache_stale_serves counter
+# HELP otter_cache_stale_serves The number of times cache lookup methods returned a value that is eligible for refresh.
+otter_cache_stale_serves_total{cache="un\"bounded\\\n"} 0
+otter_cache_stale_serves_total{cache="weighted"} 12
+# TYPE otter_cache_coalesced_loads counter
+# HELP otter_cache_coalesced_loads The number of loads and refreshes that joined a load or refresh in progress.
+otter_cache_coalesced_loads_total{cache="un\"bounded\\\n"} 0
+otter_cache_coalesced_loads_total{cache="weighted"} 3
+# TYPE otter_cache_estimated_size gauge
+# HELP otter_cache_estimated_size The approximate number of entries in the cache.
+otter_cache_estimated_size{cache="un\"bounded\\\n"} 1
+otter_cache_estimated_size{cache="weighted"} 8
+# TYPE otter_cache_weighted_size gauge
+# HELP otter_cache_weighted_size The approximate accumulated weight of entries in the cache.
+otter_cache_weighted_size{cache="weighted"} 80
+# TYPE otter_cache_maximum gauge
+# HELP otter_cache_maximum The maximum total weighted or unweighted size of the cache.
+otter_cache_maximum{cache="weighted"} 100
+# EOF
+`
+
+	var buf bytes.Buffer
+	n, err := e.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(expected)), n)
+	require.Equal(t, expected, buf.String())
+
+	e.Unregister("weighted")
+	buf.Reset()
+	_, err = e.WriteTo(&buf)
+	require.NoError(t, err)
+	require.NotContains(t, buf.String(), `cache="weighted"`)
+}
+
+func TestExporter_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	cache := otter.Must(&otter.Options[int, int]{
+		MaximumSize:   10,
+		StatsRecorder: stats.NewCounter(),
+	})
+	for i := 0; i < 20; i++ {
+		cache.Set(i, i)
+		cache.GetIfPresent(i)
+	}
+	cache.GetIfPresent(100)
+	cache.CleanUp()
+
+	e := NewExporter()
+	require.NoError(t, e.Register("ints", cache))
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	lines := strings.Split(string(body), "\n")
+	require.Contains(t, lines, `otter_cache_hits_total{cache="ints"} 20`)
+	require.Contains(t, lines, `otter_cache_misses_total{cache="ints"} 1`)
+	require.Contains(t, lines, `otter_cache_evictions_total{cache="ints"} 10`)
+	require.Contains(t, lines, `otter_cache_estimated_size{cache="ints"} 10`)
+	require.Contains(t, lines, `otter_cache_maximum{cache="ints"} 10`)
+	require.NotContains(t, string(body), "otter_cache_weighted_size{")
+	require.True(t, strings.HasSuffix(string(body), "# EOF\n"))
+}
@@ -34,6 +34,55 @@ type Recorder interface {
 	RecordLoadFailure(loadTime time.Duration)
 }
 
+// DeletionCause is the cause why a cached entry was deleted. Its values match the values of otter.DeletionCause.
+type DeletionCause int
+
+const (
+	// CauseInvalidation means that the entry was manually deleted by the user.
+	CauseInvalidation DeletionCause = iota + 1
+	// CauseReplacement means that the entry itself was not actually deleted, but its value was replaced by the user.
+	CauseReplacement
+	// CauseOverflow means that the entry was evicted due to size constraints.
+	CauseOverflow
+	// CauseExpiration means that the entry's expiration timestamp has passed.
+	CauseExpiration
+)
+
+// IsEviction returns true if there was an automatic deletion due to eviction
+// (the cause is neither [CauseInvalidation] nor [CauseReplacement]).
+func (dc DeletionCause) IsEviction() bool {
+	return !(dc == CauseInvalidation || dc == CauseReplacement)
+}
+
+// DeletionRecorder allows recording the deletions of entries broken down by their cause.
+//
+// If a [Recorder] also implements [DeletionRecorder], otter.Cache calls RecordDeletion for every deleted entry
+// instead of calling RecordEviction for the evicted ones.
+type DeletionRecorder interface {
+	// RecordDeletion records the deletion of an entry from the cache for the specified cause, including
+	// manual deletions and replacements.
+	RecordDeletion(cause DeletionCause, weight uint32)
+}
+
+// RefreshRecorder allows recording the statistics of refreshes and coalesced loads.
+//
+// The refreshes are always recorded as loads using RecordLoadSuccess and RecordLoadFailure. If a [Recorder]
+// also implements [RefreshRecorder], otter.Cache additionally records them using RecordRefreshSuccess
+// and RecordRefreshFailure, so the refreshes can be told apart from the blocking loads.
+type RefreshRecorder interface {
+	// RecordRefreshSuccess records the successful refresh of an entry. This method should be called when
+	// the refreshing completes successfully (either no error or otter.ErrNotFound).
+	RecordRefreshSuccess(loadTime time.Duration)
+	// RecordRefreshFailure records the failed refresh of an entry. This method should be called when
+	// the refreshing function returns an error that is not otter.ErrNotFound, so the stale value is kept.
+	RecordRefreshFailure(loadTime time.Duration)
+	// RecordStaleServes records that the cache lookup methods returned values that are eligible for refresh.
+	RecordStaleServes(count int)
+	// RecordCoalescedLoads records the loads and refreshes that were not started because another load or
+	// refresh of the same key was already in progress.
+	RecordCoalescedLoads(count int)
+}
+
 // Snapshoter allows getting a stats snapshot from a recorder that implements it.
 type Snapshoter interface {
 	// Snapshot returns a snapshot of this recorder's values.
@@ -49,11 +98,12 @@ type SnapshotRecorder interface {
 // NoopRecorder is a noop stats recorder. It can be useful if recording statistics is not necessary.
 type NoopRecorder struct{}
 
-func (np *NoopRecorder) RecordHits(count int)                     {}
-func (np *NoopRecorder) RecordMisses(count int)                   {}
-func (np *NoopRecorder) RecordEviction(weight uint32)             {}
-func (np *NoopRecorder) RecordLoadFailure(loadTime time.Duration) {}
-func (np *NoopRecorder) RecordLoadSuccess(loadTime time.Duration) {}
+func (np *NoopRecorder) RecordHits(count int)                              {}
+func (np *NoopRecorder) RecordMisses(count int)                            {}
+func (np *NoopRecorder) RecordEviction(weight uint32)                      {}
+func (np *NoopRecorder) RecordDeletion(cause DeletionCause, weight uint32) {}
+func (np *NoopRecorder) RecordLoadFailure(loadTime time.Duration)          {}
+func (np *NoopRecorder) RecordLoadSuccess(loadTime time.Duration)          {}
 func (np *NoopRecorder) Snapshot() Stats {
 	return Stats{}
 }
@@ -33,6 +33,14 @@ type Stats struct {
 	// EvictionWeight is the sum of weights of evicted entries. This total does not include manual
 	// otter.Cache deletions.
 	EvictionWeight uint64
+	// OverflowEvictions is the number of times an entry has been evicted due to size constraints.
+	OverflowEvictions uint64
+	// ExpirationEvictions is the number of times an entry has been evicted because its expiration timestamp has passed.
+	ExpirationEvictions uint64
+	// Invalidations is the number of times an entry has been manually deleted.
+	Invalidations uint64
+	// Replacements is the number of times the value of an entry has been replaced.
+	Replacements uint64
 	// LoadSuccesses is the number of times otter.Cache lookup methods have successfully loaded a new value.
 	LoadSuccesses uint64
 	// LoadFailures is the number of times otter.Cache lookup methods failed to load a new value, either
@@ -40,6 +48,19 @@ type Stats struct {
 	LoadFailures uint64
 	// TotalLoadTime returns the time the cache has spent loading new values.
 	TotalLoadTime time.Duration
+	// RefreshSuccesses is the number of times the cache has successfully refreshed an entry.
+	// The refreshes are also counted in LoadSuccesses.
+	RefreshSuccesses uint64
+	// RefreshFailures is the number of times the cache failed to refresh an entry and kept the stale value.
+	// The refreshes are also counted in LoadFailures.
+	RefreshFailures uint64
+	// TotalRefreshTime is the time the cache has spent refreshing entries. It's also included in TotalLoadTime.
+	TotalRefreshTime time.Duration
+	// StaleServes is the number of times otter.Cache lookup methods returned a value that is eligible for refresh.
+	StaleServes uint64
+	// CoalescedLoads is the number of loads and refreshes that were not started because another load or refresh
+	// of the same key was already in progress.
+	CoalescedLoads uint64
 }
 
 // Requests returns the number of times otter.Cache lookup methods were looking for a cached value.
@@ -101,17 +122,43 @@ func (s Stats) AverageLoadPenalty() time.Duration {
 	return s.TotalLoadTime / time.Duration(loads)
 }
 
+// Refreshes returns the total number of times that otter.Cache attempted to refresh entries.
+//
+// NOTE: the values of the metrics are undefined in case of overflow. If you require specific handling, we recommend
+// implementing your own [Recorder].
+func (s Stats) Refreshes() uint64 {
+	return saturatedAdd(s.RefreshSuccesses, s.RefreshFailures)
+}
+
+// RefreshFailureRatio returns the ratio of refresh attempts which returned errors.
+func (s Stats) RefreshFailureRatio() float64 {
+	refreshes := s.Refreshes()
+	if refreshes == 0 {
+		return 0.0
+	}
+	return float64(s.RefreshFailures) / float64(refreshes)
+}
+
 // Minus returns a new [Stats] representing the difference between this [Stats] and other.
 // Negative values, which aren't supported by [Stats] will be rounded up to zero.
 func (s Stats) Minus(other Stats) Stats {
 	return Stats{
-		Hits:           subtract(s.Hits, other.Hits),
-		Misses:         subtract(s.Misses, other.Misses),
-		Evictions:      subtract(s.Evictions, other.Evictions),
-		EvictionWeight: subtract(s.EvictionWeight, other.EvictionWeight),
-		LoadSuccesses:  subtract(s.LoadSuccesses, other.LoadSuccesses),
-		LoadFailures:   subtract(s.LoadFailures, other.LoadFailures),
-		TotalLoadTime:  subtract(s.TotalLoadTime, other.TotalLoadTime),
+		Hits:                subtract(s.Hits, other.Hits),
+		Misses:              subtract(s.Misses, other.Misses),
+		Evictions:           subtract(s.Evictions, other.Evictions),
+		EvictionWeight:      subtract(s.EvictionWeight, other.EvictionWeight),
+		OverflowEvictions:   subtract(s.OverflowEvictions, other.OverflowEvictions),
+		ExpirationEvictions: subtract(s.ExpirationEvictions, other.ExpirationEvictions),
+		Invalidations:       subtract(s.Invalidations, other.Invalidations),
+		Replacements:        subtract(s.Replacements, other.Replacements),
+		LoadSuccesses:       subtract(s.LoadSuccesses, other.LoadSuccesses),
+		LoadFailures:        subtract(s.LoadFailures, other.LoadFailures),
+		TotalLoadTime:       subtract(s.TotalLoadTime, other.TotalLoadTime),
+		RefreshSuccesses:    subtract(s.RefreshSuccesses, other.RefreshSuccesses),
+		RefreshFailures:     subtract(s.RefreshFailures, other.RefreshFailures),
+		TotalRefreshTime:    subtract(s.TotalRefreshTime, other.TotalRefreshTime),
+		StaleServes:         subtract(s.StaleServes, other.StaleServes),
+		CoalescedLoads:      subtract(s.CoalescedLoads, other.CoalescedLoads),
 	}
 }
 
@@ -122,14 +169,24 @@ func (s Stats) Minus(other Stats) Stats {
 // implementing your own stats' recorder.
 func (s Stats) Plus(other Stats) Stats {
 	totalLoadTime := xmath.SaturatedAdd(int64(s.TotalLoadTime), int64(other.TotalLoadTime))
+	totalRefreshTime := xmath.SaturatedAdd(int64(s.TotalRefreshTime), int64(other.TotalRefreshTime))
 	return Stats{
-		Hits:           saturatedAdd(s.Hits, other.Hits),
-		Misses:         saturatedAdd(s.Misses, other.Misses),
-		Evictions:      saturatedAdd(s.Evictions, other.Evictions),
-		EvictionWeight: saturatedAdd(s.EvictionWeight, other.EvictionWeight),
-		LoadSuccesses:  saturatedAdd(s.LoadSuccesses, other.LoadSuccesses),
-		LoadFailures:   saturatedAdd(s.LoadFailures, other.LoadFailures),
-		TotalLoadTime:  time.Duration(totalLoadTime),
+		Hits:                saturatedAdd(s.Hits, other.Hits),
+		Misses:              saturatedAdd(s.Misses, other.Misses),
+		Evictions:           saturatedAdd(s.Evictions, other.Evictions),
+		EvictionWeight:      saturatedAdd(s.EvictionWeight, other.EvictionWeight),
+		OverflowEvictions:   saturatedAdd(s.OverflowEvictions, other.OverflowEvictions),
+		ExpirationEvictions: saturatedAdd(s.ExpirationEvictions, other.ExpirationEvictions),
+		Invalidations:       saturatedAdd(s.Invalidations, other.Invalidations),
+		Replacements:        saturatedAdd(s.Replacements, other.Replacements),
+		LoadSuccesses:       saturatedAdd(s.LoadSuccesses, other.LoadSuccesses),
+		LoadFailures:        saturatedAdd(s.LoadFailures, other.LoadFailures),
+		TotalLoadTime:       time.Duration(totalLoadTime),
+		RefreshSuccesses:    saturatedAdd(s.RefreshSuccesses, other.RefreshSuccesses),
+		RefreshFailures:     saturatedAdd(s.RefreshFailures, other.RefreshFailures),
+		TotalRefreshTime:    time.Duration(totalRefreshTime),
+		StaleServes:         saturatedAdd(s.StaleServes, other.StaleServes),
+		CoalescedLoads:      saturatedAdd(s.CoalescedLoads, other.CoalescedLoads),
 	}
 }
 
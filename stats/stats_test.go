@@ -230,4 +230,39 @@ func TestStats(t *testing.T) {
 			0,
 		)
 	})
+
+	t.Run("deletions", func(t *testing.T) {
+		t.Parallel()
+
+		s := Stats{
+			OverflowEvictions:   5,
+			ExpirationEvictions: 7,
+			Invalidations:       11,
+			Replacements:        13,
+		}
+		other := Stats{
+			OverflowEvictions:   1,
+			ExpirationEvictions: 8,
+			Invalidations:       math.MaxUint64,
+			Replacements:        2,
+		}
+
+		expected := Stats{
+			OverflowEvictions: 4,
+			Replacements:      11,
+		}
+		if got := s.Minus(other); got != expected {
+			t.Fatalf("got = %+v, expected = %+v", got, expected)
+		}
+
+		expected = Stats{
+			OverflowEvictions:   6,
+			ExpirationEvictions: 15,
+			Invalidations:       math.MaxUint64,
+			Replacements:        15,
+		}
+		if got := s.Plus(other); got != expected {
+			t.Fatalf("got = %+v, expected = %+v", got, expected)
+		}
+	})
 }
@@ -0,0 +1,293 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// bucketsPerWindow is the number of buckets that the smallest window of a [WindowedCounter] consists of.
+const bucketsPerWindow = 12
+
+// Clock is a source of time for [WindowedCounter]. otter.Clock implements it.
+type Clock interface {
+	// NowNano returns the number of nanoseconds elapsed since this clock's zero time.
+	NowNano() int64
+}
+
+// WindowStats are statistics about the performance of an otter.Cache for the recent period of time.
+type WindowStats struct {
+	// Window is the duration of the period of time.
+	Window time.Duration
+	// Stats are statistics recorded during the period.
+	Stats Stats
+}
+
+// WindowedCounter is a goroutine-safe [Recorder] implementation that records the same statistics as [Counter]
+// and additionally maintains the statistics for rolling windows of time (for example, the last 1, 5 and 15 minutes),
+// so that alerts can be based on the recent hit ratio instead of the hit ratio since the start.
+//
+// The windows are split into buckets of the same duration, which is 1/12 of the smallest window, so the statistics
+// of a window may lag behind by the duration of a bucket.
+//
+// Unlike [Counter], all goroutines record the statistics into the same bucket, so WindowedCounter is more
+// expensive under high contention.
+type WindowedCounter struct {
+	*Counter
+	clock      Clock
+	windows    []time.Duration
+	resolution int64
+	buckets    []windowBucket
+}
+
+// NewWindowedCounter constructs a [WindowedCounter] instance with all counts initialized to zero.
+//
+// The time is measured using clock, which should be the same Clock as in the cache's options.
+// If clock is nil, the system time is used. If no windows are specified, the windows of 1, 5 and 15 minutes are used.
+//
+// NewWindowedCounter panics if any window is not positive.
+func NewWindowedCounter(clock Clock, windows ...time.Duration) *WindowedCounter {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	if len(windows) == 0 {
+		windows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+	}
+	windows = slices.Clone(windows)
+	slices.Sort(windows)
+	windows = slices.Compact(windows)
+	if windows[0] <= 0 {
+		panic("stats: window should be positive")
+	}
+
+	resolution := max(int64(windows[0])/bucketsPerWindow, 1)
+	count := (int64(windows[len(windows)-1])+resolution-1)/resolution + 1
+	buckets := make([]windowBucket, count)
+	for i := range buckets {
+		buckets[i].slot.Store(math.MinInt64)
+	}
+	return &WindowedCounter{
+		Counter:    NewCounter(),
+		clock:      clock,
+		windows:    windows,
+		resolution: resolution,
+		buckets:    buckets,
+	}
+}
+
+// Windows returns the statistics for every window, ordered from the smallest to the largest one.
+// Note that this may be an inconsistent view, as it may be interleaved with update operations.
+func (c *WindowedCounter) Windows() []WindowStats {
+	result := make([]WindowStats, 0, len(c.windows))
+	for _, w := range c.windows {
+		result = append(result, WindowStats{
+			Window: w,
+			Stats:  c.Window(w),
+		})
+	}
+	return result
+}
+
+// Window returns the statistics for the last d. d is rounded up to the duration of a bucket and
+// limited by the largest window. Note that this may be an inconsistent view, as it may be interleaved
+// with update operations.
+func (c *WindowedCounter) Window(d time.Duration) Stats {
+	current := c.slot()
+	count := min((int64(d)+c.resolution-1)/c.resolution, int64(len(c.buckets)-1))
+
+	var counts [counterCount]uint64
+	for i := range c.buckets {
+		b := &c.buckets[i]
+		if slot := b.slot.Load(); slot > current-count && slot <= current {
+			for j := range counts {
+				counts[j] += b.counts[j].Load()
+			}
+		}
+	}
+	return Stats{
+		Hits:                counts[hitsCounter],
+		Misses:              counts[missesCounter],
+		Evictions:           counts[evictionsCounter],
+		EvictionWeight:      counts[evictionWeightCounter],
+		OverflowEvictions:   counts[overflowsCounter],
+		ExpirationEvictions: counts[expirationsCounter],
+		Invalidations:       counts[invalidationsCounter],
+		Replacements:        counts[replacementsCounter],
+		LoadSuccesses:       counts[loadSuccessesCounter],
+		LoadFailures:        counts[loadFailuresCounter],
+		TotalLoadTime:       toDuration(counts[totalLoadTimeCounter]),
+		RefreshSuccesses:    counts[refreshSuccessesCounter],
+		RefreshFailures:     counts[refreshFailuresCounter],
+		TotalRefreshTime:    toDuration(counts[totalRefreshTimeCounter]),
+		StaleServes:         counts[staleServesCounter],
+		CoalescedLoads:      counts[coalescedLoadsCounter],
+	}
+}
+
+// RecordHits records cache hits. This should be called when a cache request returns a cached value.
+func (c *WindowedCounter) RecordHits(count int) {
+	c.Counter.RecordHits(count)
+	c.add(hitsCounter, count)
+}
+
+// RecordMisses records cache misses. This should be called when a cache request returns a value that was not
+// found in the cache.
+func (c *WindowedCounter) RecordMisses(count int) {
+	c.Counter.RecordMisses(count)
+	c.add(missesCounter, count)
+}
+
+// RecordEviction records the eviction of an entry from the cache. This should only been called when an entry is
+// evicted due to the cache's eviction strategy, and not as a result of manual deletions.
+func (c *WindowedCounter) RecordEviction(weight uint32) {
+	c.Counter.RecordEviction(weight)
+	b := c.bucket()
+	b.counts[evictionsCounter].Add(1)
+	b.counts[evictionWeightCounter].Add(uint64(weight))
+}
+
+// RecordDeletion records the deletion of an entry from the cache for the specified cause, including
+// manual deletions and replacements. The evictions are also counted by RecordEviction.
+func (c *WindowedCounter) RecordDeletion(cause DeletionCause, weight uint32) {
+	c.Counter.RecordDeletion(cause, weight)
+	b := c.bucket()
+	switch cause {
+	case CauseInvalidation:
+		b.counts[invalidationsCounter].Add(1)
+	case CauseReplacement:
+		b.counts[replacementsCounter].Add(1)
+	case CauseOverflow:
+		b.counts[overflowsCounter].Add(1)
+	case CauseExpiration:
+		b.counts[expirationsCounter].Add(1)
+	}
+	if cause.IsEviction() {
+		b.counts[evictionsCounter].Add(1)
+		b.counts[evictionWeightCounter].Add(uint64(weight))
+	}
+}
+
+// RecordLoadSuccess records the successful load of a new entry. This method should be called when a cache request
+// causes an entry to be loaded and the loading completes successfully (either no error or otter.ErrNotFound).
+func (c *WindowedCounter) RecordLoadSuccess(loadTime time.Duration) {
+	c.Counter.RecordLoadSuccess(loadTime)
+	c.addTime(loadSuccessesCounter, totalLoadTimeCounter, loadTime)
+}
+
+// RecordLoadFailure records the failed load of a new entry. This method should be called when a cache request
+// causes an entry to be loaded, but the loading function returns an error that is not otter.ErrNotFound.
+func (c *WindowedCounter) RecordLoadFailure(loadTime time.Duration) {
+	c.Counter.RecordLoadFailure(loadTime)
+	c.addTime(loadFailuresCounter, totalLoadTimeCounter, loadTime)
+}
+
+// RecordRefreshSuccess records the successful refresh of an entry. This method should be called when
+// the refreshing completes successfully (either no error or otter.ErrNotFound).
+func (c *WindowedCounter) RecordRefreshSuccess(loadTime time.Duration) {
+	c.Counter.RecordRefreshSuccess(loadTime)
+	c.addTime(refreshSuccessesCounter, totalRefreshTimeCounter, loadTime)
+}
+
+// RecordRefreshFailure records the failed refresh of an entry. This method should be called when
+// the refreshing function returns an error that is not otter.ErrNotFound, so the stale value is kept.
+func (c *WindowedCounter) RecordRefreshFailure(loadTime time.Duration) {
+	c.Counter.RecordRefreshFailure(loadTime)
+	c.addTime(refreshFailuresCounter, totalRefreshTimeCounter, loadTime)
+}
+
+// RecordStaleServes records that the cache lookup methods returned values that are eligible for refresh.
+func (c *WindowedCounter) RecordStaleServes(count int) {
+	c.Counter.RecordStaleServes(count)
+	c.add(staleServesCounter, count)
+}
+
+// RecordCoalescedLoads records the loads and refreshes that were not started because another load or
+// refresh of the same key was already in progress.
+func (c *WindowedCounter) RecordCoalescedLoads(count int) {
+	c.Counter.RecordCoalescedLoads(count)
+	c.add(coalescedLoadsCounter, count)
+}
+
+func (c *WindowedCounter) add(counter int, count int) {
+	//nolint:gosec // there is no overflow
+	c.bucket().counts[counter].Add(uint64(count))
+}
+
+func (c *WindowedCounter) addTime(counter, timeCounter int, d time.Duration) {
+	b := c.bucket()
+	b.counts[counter].Add(1)
+	//nolint:gosec // there is no overflow
+	b.counts[timeCounter].Add(uint64(d))
+}
+
+func (c *WindowedCounter) slot() int64 {
+	return c.clock.NowNano() / c.resolution
+}
+
+// bucket returns the bucket of the current slot, resetting it if it contains the statistics of an old slot.
+//
+// The statistics recorded concurrently with the reset may be lost.
+func (c *WindowedCounter) bucket() *windowBucket {
+	slot := c.slot()
+	//nolint:gosec // the length of buckets is positive
+	b := &c.buckets[uint64(slot)%uint64(len(c.buckets))]
+	if old := b.slot.Load(); old < slot && b.slot.CompareAndSwap(old, slot) {
+		for i := range b.counts {
+			b.counts[i].Store(0)
+		}
+	}
+	return b
+}
+
+const (
+	hitsCounter = iota
+	missesCounter
+	evictionsCounter
+	evictionWeightCounter
+	overflowsCounter
+	expirationsCounter
+	invalidationsCounter
+	replacementsCounter
+	loadSuccessesCounter
+	loadFailuresCounter
+	totalLoadTimeCounter
+	refreshSuccessesCounter
+	refreshFailuresCounter
+	totalRefreshTimeCounter
+	staleServesCounter
+	coalescedLoadsCounter
+	counterCount
+)
+
+type windowBucket struct {
+	slot   atomic.Int64
+	counts [counterCount]atomic.Uint64
+}
+
+type systemClock struct{}
+
+func (systemClock) NowNano() int64 {
+	return time.Now().UnixNano()
+}
+
+func toDuration(v uint64) time.Duration {
+	if v > uint64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(v)
+}
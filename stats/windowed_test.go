@@ -0,0 +1,166 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type manualClock struct {
+	now atomic.Int64
+}
+
+func (mc *manualClock) NowNano() int64 {
+	return mc.now.Load()
+}
+
+func (mc *manualClock) advance(d time.Duration) {
+	mc.now.Add(int64(d))
+}
+
+func TestWindowedCounter(t *testing.T) {
+	t.Parallel()
+
+	mc := &manualClock{}
+	mc.now.Store(time.Now().UnixNano())
+	c := NewWindowedCounter(mc)
+
+	windows := func() []Stats {
+		t.Helper()
+
+		ws := c.Windows()
+		expected := []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+		if len(ws) != len(expected) {
+			t.Fatalf("windows should be %v, but got %v", expected, ws)
+		}
+		result := make([]Stats, 0, len(ws))
+		for i, w := range ws {
+			if w.Window != expected[i] {
+				t.Fatalf("window should be %s, but got %s", expected[i], w.Window)
+			}
+			result = append(result, w.Stats)
+		}
+		return result
+	}
+
+	// a lot of hits 10 minutes ago.
+	c.RecordHits(90)
+	c.RecordMisses(10)
+	c.RecordLoadSuccess(time.Second)
+	c.RecordDeletion(CauseOverflow, 2)
+	mc.advance(10 * time.Minute)
+
+	// only misses 2 minutes ago.
+	c.RecordMisses(5)
+	c.RecordRefreshFailure(time.Second)
+	mc.advance(2 * time.Minute)
+
+	// a half of the requests are hits now.
+	c.RecordHits(5)
+	c.RecordMisses(5)
+	c.RecordStaleServes(3)
+
+	ws := windows()
+	if ws[0].HitRatio() != 0.5 || ws[0].Requests() != 10 || ws[0].StaleServes != 3 {
+		t.Fatalf("the last minute stats are not correct: %+v", ws[0])
+	}
+	if ws[1].Requests() != 15 || ws[1].RefreshFailures != 1 || ws[1].TotalRefreshTime != time.Second {
+		t.Fatalf("the last 5 minutes stats are not correct: %+v", ws[1])
+	}
+	expected := Stats{
+		Hits:              95,
+		Misses:            20,
+		Evictions:         1,
+		EvictionWeight:    2,
+		OverflowEvictions: 1,
+		LoadSuccesses:     1,
+		TotalLoadTime:     time.Second,
+		RefreshFailures:   1,
+		TotalRefreshTime:  time.Second,
+		StaleServes:       3,
+	}
+	if ws[2] != expected {
+		t.Fatalf("got = %+v, expected = %+v", ws[2], expected)
+	}
+	if got := c.Snapshot(); got != expected {
+		t.Fatalf("cumulative stats should be %+v, but got %+v", expected, got)
+	}
+
+	// the old buckets are reused.
+	mc.advance(20 * time.Minute)
+	c.RecordHits(1)
+	ws = windows()
+	for _, s := range ws {
+		if s != (Stats{Hits: 1}) {
+			t.Fatalf("only the last hit should be recorded, but got %+v", s)
+		}
+	}
+	if got := c.Window(time.Hour); got != (Stats{Hits: 1}) {
+		t.Fatalf("the window should be limited by the largest one, but got %+v", got)
+	}
+	if got := c.Snapshot().Hits; got != 96 {
+		t.Fatalf("cumulative hits should be 96, but got %d", got)
+	}
+}
+
+func TestWindowedCounter_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	c := NewWindowedCounter(nil, time.Hour)
+
+	goroutines := 50
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+
+			c.RecordHits(1)
+			c.RecordMisses(1)
+			c.RecordEviction(10)
+			c.RecordLoadSuccess(1)
+			c.RecordLoadFailure(1)
+		}()
+	}
+
+	wg.Wait()
+
+	expected := Stats{
+		Hits:           50,
+		Misses:         50,
+		Evictions:      50,
+		EvictionWeight: 500,
+		LoadSuccesses:  50,
+		LoadFailures:   50,
+		TotalLoadTime:  100,
+	}
+	if got := c.Window(time.Hour); got != expected {
+		t.Fatalf("got = %+v, expected = %+v", got, expected)
+	}
+}
+
+func TestWindowedCounter_InvalidWindow(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("NewWindowedCounter should panic on a non-positive window")
+		}
+	}()
+	NewWindowedCounter(nil, time.Minute, 0)
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type loadResult[R any] struct {
+	res R
+	err error
+}
+
+// withTimeout returns a function that calls load with a context that is canceled after the timeout
+// and returns ErrLoadTimeout as soon as the timeout expires, even if load ignores the context.
+// In this case load keeps running in its own goroutine, and its result is discarded.
+// If timeout is not positive, load is returned as is.
+func withTimeout[A, R any](
+	timeout time.Duration,
+	load func(ctx context.Context, arg A) (R, error),
+) func(ctx context.Context, arg A) (R, error) {
+	if timeout <= 0 {
+		return load
+	}
+
+	return func(ctx context.Context, arg A) (R, error) {
+		ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrLoadTimeout)
+		defer cancel()
+
+		done := make(chan loadResult[R], 1)
+		go func() {
+			var lr loadResult[R]
+			defer func() {
+				if r := recover(); r != nil {
+					// the panic is rethrown by the cache in the goroutines waiting for the load.
+					lr.err = newPanicError(r)
+				}
+				done <- lr
+			}()
+
+			lr.res, lr.err = load(ctx, arg)
+		}()
+
+		select {
+		case lr := <-done:
+			if lr.err != nil && errors.Is(context.Cause(ctx), ErrLoadTimeout) {
+				// the loader has returned the error of its context canceled by the timeout.
+				return zeroValue[R](), context.Cause(ctx)
+			}
+			return lr.res, lr.err
+		case <-ctx.Done():
+			return zeroValue[R](), context.Cause(ctx)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_LoadTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := Must(&Options[int, int]{
+		LoadTimeout: 20 * time.Millisecond,
+	})
+
+	var once sync.Once
+	started := make(chan struct{})
+	release := make(chan struct{})
+	// the loader ignores its context.
+	loader := LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		once.Do(func() {
+			close(started)
+		})
+		<-release
+		return key, nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := c.Get(ctx, 1, loader)
+		require.ErrorIs(t, err, ErrLoadTimeout)
+	}()
+	<-started
+	// the waiter is released too.
+	_, err := c.Get(ctx, 1, loader)
+	require.ErrorIs(t, err, ErrLoadTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	wg.Wait()
+
+	// the late result is discarded.
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	_, ok := c.GetIfPresent(1)
+	require.False(t, ok)
+
+	v, err := c.Get(ctx, 2, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		_, ok := ctx.Deadline()
+		require.True(t, ok)
+		return key, nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+
+	// the loader respects its context.
+	_, err = c.Get(ctx, 3, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, err, ErrLoadTimeout)
+
+	require.Panics(t, func() {
+		_, _ = c.Get(ctx, 4, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+			panic("boom")
+		}))
+	})
+}
+
+func TestCache_BulkLoadTimeout(t *testing.T) {
+	t.Parallel()
+
+	c := Must(&Options[int, int]{
+		LoadTimeout: 20 * time.Millisecond,
+	})
+
+	release := make(chan struct{})
+	defer close(release)
+	result, err := c.BulkGet(context.Background(), []int{1, 2}, BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		<-release
+		return map[int]int{1: 1, 2: 2}, nil
+	}))
+	require.ErrorIs(t, err, ErrLoadTimeout)
+	require.Empty(t, result)
+}
+
+func TestCache_RefreshTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := Must(&Options[int, int]{
+		RefreshCalculator: RefreshWriting[int, int](time.Hour),
+		RefreshTimeout:    20 * time.Millisecond,
+		Logger:            &NoopLogger{},
+	})
+	c.Set(1, 1)
+	c.Set(2, 2)
+
+	release := make(chan struct{})
+	defer close(release)
+	res := <-c.Refresh(ctx, 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		<-release
+		return 10, nil
+	}))
+	require.ErrorIs(t, res.Err, ErrLoadTimeout)
+	v, ok := c.GetIfPresent(1)
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+
+	results := <-c.BulkRefresh(ctx, []int{2, 3}, BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		<-release
+		return nil, nil
+	}))
+	require.Len(t, results, 2)
+	for _, r := range results {
+		require.ErrorIs(t, r.Err, ErrLoadTimeout)
+	}
+	v, ok = c.GetIfPresent(2)
+	require.True(t, ok)
+	require.Equal(t, 2, v)
+}
+
+func TestCache_RefreshTimeoutWithoutRefreshCalculator(t *testing.T) {
+	t.Parallel()
+
+	c, err := New(&Options[int, int]{
+		RefreshTimeout: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	c.Set(1, 1)
+
+	// the cache doesn't refresh entries without RefreshCalculator, so the timeout is not used.
+	require.Nil(t, c.Refresh(context.Background(), 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		panic("unreachable")
+	})))
+	v, ok := c.GetIfPresent(1)
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+}
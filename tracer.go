@@ -0,0 +1,82 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import "context"
+
+// LoadOperation is the kind of the operation that obtains values from the data source.
+type LoadOperation int
+
+const (
+	// OperationLoad means that a single value is loaded by Cache.Get.
+	OperationLoad LoadOperation = iota + 1
+	// OperationRefresh means that a single value is refreshed by Cache.Refresh or
+	// by Cache.Get, if RefreshCalculator was specified.
+	OperationRefresh
+	// OperationBulkLoad means that values are loaded by Cache.BulkGet.
+	OperationBulkLoad
+	// OperationBulkRefresh means that values are refreshed by Cache.BulkRefresh or
+	// by Cache.BulkGet, if RefreshCalculator was specified.
+	OperationBulkRefresh
+)
+
+var loadOperationStrings = []string{
+	"Load",
+	"Refresh",
+	"BulkLoad",
+	"BulkRefresh",
+}
+
+// String implements [fmt.Stringer] interface.
+func (op LoadOperation) String() string {
+	if op >= 1 && int(op) <= len(loadOperationStrings) {
+		return loadOperationStrings[op-1]
+	}
+	return "<unknown otter.LoadOperation>"
+}
+
+// LoadInfo describes the operation that obtains values from the data source.
+type LoadInfo struct {
+	// Operation is the kind of the operation.
+	Operation LoadOperation
+	// KeyCount is the number of keys obtained by the operation.
+	KeyCount int
+	// Coalesced is true if the caller didn't call the loader, but waited for the keys
+	// that were already being loaded by another call.
+	Coalesced bool
+}
+
+// Tracer is the interface used to intercept the operations that obtain values from the data source,
+// so that the loads can be made visible in traces. For example, a thin adapter can start an OpenTelemetry span
+// in StartLoad and end it in EndLoad.
+//
+// The methods are called synchronously by the goroutine performing the operation, so they should be fast.
+type Tracer interface {
+	// StartLoad is called before the operation starts. The returned context is passed to the loader
+	// (for refreshes, without its cancellation) and to EndLoad.
+	StartLoad(ctx context.Context, info LoadInfo) context.Context
+	// EndLoad is called after the operation completes with the context returned by StartLoad and
+	// the error of the operation (if any). Note that err can be ErrNotFound.
+	EndLoad(ctx context.Context, info LoadInfo, err error)
+}
+
+// NoopTracer is a stub implementation of [Tracer] interface.
+type NoopTracer struct{}
+
+func (nt *NoopTracer) StartLoad(ctx context.Context, info LoadInfo) context.Context {
+	return ctx
+}
+
+func (nt *NoopTracer) EndLoad(ctx context.Context, info LoadInfo, err error) {}
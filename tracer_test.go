@@ -0,0 +1,269 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+type testSpan struct {
+	info  LoadInfo
+	err   error
+	ended bool
+}
+
+type testTracer struct {
+	mutex sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) StartLoad(ctx context.Context, info LoadInfo) context.Context {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	s := &testSpan{info: info}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+func (t *testTracer) EndLoad(ctx context.Context, info LoadInfo, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	//nolint:errcheck // the context is returned by StartLoad
+	s := ctx.Value(spanKey{}).(*testSpan)
+	s.err = err
+	s.ended = true
+}
+
+func (t *testTracer) getSpans() []testSpan {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	spans := make([]testSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		spans = append(spans, *s)
+	}
+	return spans
+}
+
+func spanFromContext(ctx context.Context) *testSpan {
+	s, _ := ctx.Value(spanKey{}).(*testSpan)
+	return s
+}
+
+func TestLoadOperation_String(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Load", OperationLoad.String())
+	require.Equal(t, "Refresh", OperationRefresh.String())
+	require.Equal(t, "BulkLoad", OperationBulkLoad.String())
+	require.Equal(t, "BulkRefresh", OperationBulkRefresh.String())
+	require.Equal(t, "<unknown otter.LoadOperation>", LoadOperation(0).String())
+}
+
+func TestNoopTracer(t *testing.T) {
+	t.Parallel()
+
+	o := &Options[int, int]{Tracer: &NoopTracer{}}
+	require.Nil(t, o.getTracer())
+
+	ctx := context.Background()
+	nt := &NoopTracer{}
+	require.Equal(t, ctx, nt.StartLoad(ctx, LoadInfo{}))
+	nt.EndLoad(ctx, LoadInfo{}, nil)
+}
+
+func TestCache_TraceLoad(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tracer := &testTracer{}
+	c := Must(&Options[int, int]{
+		Tracer: tracer,
+	})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var loaderSpan *testSpan
+	loader := LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		loaderSpan = spanFromContext(ctx)
+		close(started)
+		<-release
+		return key, nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v, err := c.Get(ctx, 1, loader)
+		require.NoError(t, err)
+		require.Equal(t, 1, v)
+	}()
+	<-started
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v, err := c.Get(ctx, 1, loader)
+		require.NoError(t, err)
+		require.Equal(t, 1, v)
+	}()
+	require.Eventually(t, func() bool {
+		return len(tracer.getSpans()) == 2
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	spans := tracer.getSpans()
+	require.Equal(t, []testSpan{
+		{info: LoadInfo{Operation: OperationLoad, KeyCount: 1}, ended: true},
+		{info: LoadInfo{Operation: OperationLoad, KeyCount: 1, Coalesced: true}, ended: true},
+	}, spans)
+	require.NotNil(t, loaderSpan)
+	require.Equal(t, spans[0], *loaderSpan)
+
+	// hits are not traced.
+	_, err := c.Get(ctx, 1, loader)
+	require.NoError(t, err)
+	require.Len(t, tracer.getSpans(), 2)
+
+	_, err = c.Get(ctx, 2, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		return 0, ErrNotFound
+	}))
+	require.ErrorIs(t, err, ErrNotFound)
+	spans = tracer.getSpans()
+	require.Len(t, spans, 3)
+	require.ErrorIs(t, spans[2].err, ErrNotFound)
+}
+
+func TestCache_TraceBulkLoad(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tracer := &testTracer{}
+	c := Must(&Options[int, int]{
+		Tracer: tracer,
+	})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _ = c.Get(ctx, 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+			close(started)
+			<-release
+			return key, nil
+		}))
+	}()
+	<-started
+	go func() {
+		// wait for BulkGet to start waiting for the load of Get.
+		require.Eventually(t, func() bool {
+			return len(tracer.getSpans()) == 3
+		}, time.Second, time.Millisecond)
+		close(release)
+	}()
+
+	loadErr := errors.New("failed")
+	result, err := c.BulkGet(ctx, []int{1, 2, 3}, BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		require.NotNil(t, spanFromContext(ctx))
+		require.Len(t, keys, 2)
+		return map[int]int{2: 2, 3: 3}, nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 1, 2: 2, 3: 3}, result)
+
+	_, err = c.BulkGet(ctx, []int{4, 5}, BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		return nil, loadErr
+	}))
+	require.ErrorIs(t, err, loadErr)
+
+	require.Equal(t, []testSpan{
+		{info: LoadInfo{Operation: OperationLoad, KeyCount: 1}, ended: true},
+		{info: LoadInfo{Operation: OperationBulkLoad, KeyCount: 2}, ended: true},
+		{info: LoadInfo{Operation: OperationBulkLoad, KeyCount: 1, Coalesced: true}, ended: true},
+		{info: LoadInfo{Operation: OperationBulkLoad, KeyCount: 2}, err: loadErr, ended: true},
+	}, tracer.getSpans())
+}
+
+func TestCache_TraceRefresh(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tracer := &testTracer{}
+	c := Must(&Options[int, int]{
+		RefreshCalculator: RefreshWriting[int, int](time.Hour),
+		Logger:            &NoopLogger{},
+		Tracer:            tracer,
+	})
+	c.Set(1, 1)
+
+	var refreshSpan *testSpan
+	refreshErr := errors.New("failed")
+	loader := LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+		refreshSpan = spanFromContext(ctx)
+		return 0, refreshErr
+	})
+	require.ErrorIs(t, (<-c.Refresh(ctx, 1, loader)).Err, refreshErr)
+
+	bulkLoader := BulkLoaderFunc[int, int](func(ctx context.Context, keys []int) (map[int]int, error) {
+		require.NotNil(t, spanFromContext(ctx))
+		result := make(map[int]int, len(keys))
+		for _, k := range keys {
+			result[k] = k
+		}
+		return result, nil
+	})
+	results := <-c.BulkRefresh(ctx, []int{1, 2, 3}, bulkLoader)
+	require.Len(t, results, 3)
+
+	spans := tracer.getSpans()
+	require.NotNil(t, refreshSpan)
+	require.Equal(t, spans[0], *refreshSpan)
+	require.Equal(t, []testSpan{
+		{info: LoadInfo{Operation: OperationRefresh, KeyCount: 1}, err: refreshErr, ended: true},
+		{info: LoadInfo{Operation: OperationBulkRefresh, KeyCount: 2}, ended: true},
+		{info: LoadInfo{Operation: OperationBulkRefresh, KeyCount: 1}, ended: true},
+	}, spans)
+}
+
+func TestCache_TraceLoadPanic(t *testing.T) {
+	t.Parallel()
+
+	tracer := &testTracer{}
+	c := Must(&Options[int, int]{
+		Tracer: tracer,
+	})
+
+	require.Panics(t, func() {
+		_, _ = c.Get(context.Background(), 1, LoaderFunc[int, int](func(ctx context.Context, key int) (int, error) {
+			panic("boom")
+		}))
+	})
+
+	spans := tracer.getSpans()
+	require.Len(t, spans, 1)
+	require.True(t, spans[0].ended)
+	var pe *panicError
+	require.ErrorAs(t, spans[0].err, &pe)
+}
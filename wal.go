@@ -0,0 +1,646 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The log segment format is:
+//
+//	header: magic [8]byte | version uint16 | fingerprint uint64
+//	record: uvarint(len) | op uint8 | payload [len-1]byte | checksum uint32
+//
+// All fixed-size integers are big-endian. The payload of every record is the output of a single
+// EntryEncoder.Encode call of the segment's encoder, and the checksum is the CRC-32C of the op and the payload.
+// A set record contains the whole entry, a delete record contains only its key.
+//
+// A segment is replayed until its end or the first incomplete or corrupted record,
+// which is the expected result of a crash during writing.
+const (
+	walMagic         = "OTTERWAL"
+	walVersion       = uint16(1)
+	walSnapshotName  = "snapshot"
+	walSegmentPrefix = "wal-"
+	walSegmentSuffix = ".log"
+)
+
+type walOp uint8
+
+const (
+	walSet walOp = iota + 1
+	walDelete
+)
+
+const (
+	defaultWALSyncInterval       = time.Second
+	defaultWALCompactionInterval = 10 * time.Minute
+)
+
+// WALOptions configures a [WAL].
+//
+// The zero value is ready to use.
+type WALOptions[K comparable, V any] struct {
+	// Codec specifies the codec used to encode the log records and the snapshots.
+	//
+	// By default, GobCodec is used.
+	Codec Codec[K, V]
+	// SyncInterval specifies how often the recorded changes are written to the log and synced to stable storage.
+	// This is the maximum amount of changes (measured in time) that may be lost after a crash.
+	//
+	// By default, SyncInterval is one second.
+	SyncInterval time.Duration
+	// CompactionInterval specifies how often the log is compacted into a full snapshot.
+	//
+	// By default, CompactionInterval is ten minutes.
+	CompactionInterval time.Duration
+	// Compressor specifies the compression of the snapshots.
+	//
+	// By default, the snapshots are not compressed.
+	Compressor Compressor
+}
+
+func (o *WALOptions[K, V]) getCodec() Codec[K, V] {
+	if o.Codec == nil {
+		return GobCodec[K, V]{}
+	}
+	return o.Codec
+}
+
+func (o *WALOptions[K, V]) getSyncInterval() time.Duration {
+	if o.SyncInterval <= 0 {
+		return defaultWALSyncInterval
+	}
+	return o.SyncInterval
+}
+
+func (o *WALOptions[K, V]) getCompactionInterval() time.Duration {
+	if o.CompactionInterval <= 0 {
+		return defaultWALCompactionInterval
+	}
+	return o.CompactionInterval
+}
+
+type walRecord[K comparable, V any] struct {
+	op    walOp
+	entry Entry[K, V]
+}
+
+// WAL is an append-only write-ahead log of the changes of a [Cache].
+//
+// WAL records insertions, updates, invalidations, evictions and expirations of the cache entries,
+// as well as the deadlines changed by Cache.SetExpiresAfter and Cache.SetRefreshableAfter,
+// and appends them to log segments in a directory every SyncInterval. Every CompactionInterval, the log is
+// compacted into a full snapshot of the cache, so a restarted application recovers the cache using
+// the snapshot and the few remaining log segments.
+//
+// The expiration times extended by ExpiryCalculator.ExpireAfterRead are not recorded, because logging
+// every read would be too expensive. The recovered entries therefore keep the expiration times of
+// their last writes and may expire earlier than in the original cache, until the next compaction saves them.
+//
+// The WAL must be closed with Close, which takes the final snapshot.
+// The Cache is not garbage collected until its WAL is closed.
+type WAL[K comparable, V any] struct {
+	cache              *Cache[K, V]
+	dir                string
+	codec              Codec[K, V]
+	compressor         Compressor
+	clock              timeSource
+	compactionInterval time.Duration
+	// mutex guards the pending records.
+	mutex   sync.Mutex
+	pending []walRecord[K, V]
+	// fileMutex guards the current segment.
+	fileMutex      sync.Mutex
+	segment        *walSegmentWriter[K, V]
+	seq            uint64
+	lastCompaction int64
+	closed         bool
+	closeOnce      sync.Once
+	closeErr       error
+	done           chan struct{}
+	stopped        chan struct{}
+}
+
+// OpenWAL recovers the cache from the snapshot and the log segments in dir (if any)
+// and starts logging the changes of the cache.
+//
+// The cache should not be modified until OpenWAL returns. The recovered entries are inserted
+// the same way as by LoadCacheFrom. Only one WAL may be attached to a cache at a time.
+//
+// If several WALOptions are specified, only the last non-nil one is used.
+func OpenWAL[K comparable, V any](c *Cache[K, V], dir string, opts ...*WALOptions[K, V]) (*WAL[K, V], error) {
+	o := lastOptions(opts)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("otter: create dir %s: %w", dir, err)
+	}
+
+	clock := c.cache.clock
+	clock.Init()
+	w := &WAL[K, V]{
+		cache:              c,
+		dir:                dir,
+		codec:              o.getCodec(),
+		compressor:         o.Compressor,
+		clock:              clock,
+		compactionInterval: o.getCompactionInterval(),
+		lastCompaction:     clock.NowNano(),
+		done:               make(chan struct{}),
+		stopped:            make(chan struct{}),
+	}
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+	// the recovered entries must not be logged again.
+	c.CleanUp()
+
+	if !c.cache.wal.CompareAndSwap(nil, w) {
+		return nil, errors.New("otter: the cache already has a WAL")
+	}
+	if err := w.openSegment(); err != nil {
+		c.cache.wal.Store(nil)
+		return nil, err
+	}
+
+	go w.run(o.getSyncInterval())
+	return w, nil
+}
+
+// recover loads the snapshot and replays the log segments.
+func (w *WAL[K, V]) recover() error {
+	lo := &LoadOptions[K, V]{
+		Codec: w.codec,
+	}
+	if w.compressor != nil {
+		lo.Compressors = []Compressor{w.compressor}
+	}
+	err := LoadCacheFromFile(w.cache, filepath.Join(w.dir, walSnapshotName), lo)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	seqs, err := w.segments()
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		if err := w.replay(seq); err != nil {
+			return err
+		}
+		w.seq = seq
+	}
+	return nil
+}
+
+// segments returns the sequence numbers of the log segments in ascending order.
+func (w *WAL[K, V]) segments() ([]uint64, error) {
+	files, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("otter: read dir %s: %w", w.dir, err)
+	}
+
+	var seqs []uint64
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, walSegmentPrefix) || !strings.HasSuffix(name, walSegmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, walSegmentPrefix), walSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	return seqs, nil
+}
+
+func (w *WAL[K, V]) segmentPath(seq uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%020d%s", walSegmentPrefix, seq, walSegmentSuffix))
+}
+
+// replay applies the records of the segment to the cache.
+func (w *WAL[K, V]) replay(seq uint64) error {
+	path := w.segmentPath(seq)
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("otter: open file %s: %w", path, err)
+	}
+	//nolint:errcheck // it's ok
+	defer file.Close()
+
+	sr := newWALSegmentReader(file)
+	if err := sr.readHeader(typeFingerprint[K, V]()); err != nil {
+		if errors.Is(err, ErrCorruptedSnapshot) {
+			// the segment was being created during the crash.
+			return nil
+		}
+		return fmt.Errorf("otter: replay %s: %w", path, err)
+	}
+
+	dec := w.codec.NewDecoder(sr)
+	for {
+		var entry Entry[K, V]
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("otter: replay %s: decode record: %w", path, err)
+		}
+
+		switch sr.nextOp() {
+		case walSet:
+			if !loadEntry(w.cache, entry) {
+				// the set has already expired, so it must not resurrect an older value of the key.
+				w.cache.Invalidate(entry.Key)
+			}
+		case walDelete:
+			w.cache.Invalidate(entry.Key)
+		}
+	}
+}
+
+// openSegment starts a new log segment. It must be called with the fileMutex held or before the WAL is started.
+func (w *WAL[K, V]) openSegment() error {
+	seq := w.seq + 1
+	path := w.segmentPath(seq)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("otter: create file %s: %w", path, err)
+	}
+
+	segment := newWALSegmentWriter(file, w.codec)
+	if err := segment.writeHeader(typeFingerprint[K, V]()); err != nil {
+		//nolint:errcheck // the original error is more important
+		segment.close()
+		//nolint:errcheck // the original error is more important
+		os.Remove(path)
+		return fmt.Errorf("otter: write %s: %w", path, err)
+	}
+	if err := syncDir(w.dir); err != nil {
+		//nolint:errcheck // the original error is more important
+		segment.close()
+		return err
+	}
+
+	w.segment = segment
+	w.seq = seq
+	return nil
+}
+
+func (w *WAL[K, V]) recordSet(entry Entry[K, V]) {
+	w.mutex.Lock()
+	w.pending = append(w.pending, walRecord[K, V]{op: walSet, entry: entry})
+	w.mutex.Unlock()
+}
+
+func (w *WAL[K, V]) recordDelete(key K) {
+	w.mutex.Lock()
+	w.pending = append(w.pending, walRecord[K, V]{op: walDelete, entry: Entry[K, V]{Key: key}})
+	w.mutex.Unlock()
+}
+
+func (w *WAL[K, V]) run(syncInterval time.Duration) {
+	defer close(w.stopped)
+
+	tick := w.clock.Tick(syncInterval)
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-tick:
+			var err error
+			if w.clock.NowNano()-w.lastCompactionNano() >= int64(w.compactionInterval) {
+				err = w.Compact()
+			} else {
+				err = w.Sync()
+			}
+			if err != nil {
+				w.cache.cache.logger.Error(context.Background(), "Failed to write the cache WAL", err)
+			}
+		}
+	}
+}
+
+func (w *WAL[K, V]) lastCompactionNano() int64 {
+	w.fileMutex.Lock()
+	defer w.fileMutex.Unlock()
+
+	return w.lastCompaction
+}
+
+// Sync writes the recorded changes to the log and syncs it to stable storage.
+func (w *WAL[K, V]) Sync() error {
+	w.fileMutex.Lock()
+	defer w.fileMutex.Unlock()
+
+	return w.sync()
+}
+
+func (w *WAL[K, V]) sync() error {
+	if w.segment == nil {
+		if w.closed {
+			return errors.New("otter: WAL is closed")
+		}
+		// the last compaction failed to open a new segment.
+		if err := w.openSegment(); err != nil {
+			// the records are dropped instead of piling up, and the next tick compacts the log,
+			// so that the snapshot contains the dropped changes.
+			w.mutex.Lock()
+			w.pending = nil
+			w.mutex.Unlock()
+			w.lastCompaction = w.clock.NowNano() - int64(w.compactionInterval)
+			return err
+		}
+	}
+
+	w.mutex.Lock()
+	records := w.pending
+	w.pending = nil
+	w.mutex.Unlock()
+
+	for _, r := range records {
+		if err := w.segment.writeRecord(r); err != nil {
+			return fmt.Errorf("otter: write record: %w", err)
+		}
+	}
+	return w.segment.sync()
+}
+
+// Compact saves a full snapshot of the cache and removes the log segments that it covers.
+//
+// The changes made during the compaction are written to a new log segment, so the cache is
+// recovered correctly even if the compaction is interrupted by a crash.
+func (w *WAL[K, V]) Compact() error {
+	w.fileMutex.Lock()
+	defer w.fileMutex.Unlock()
+
+	return w.compact(true)
+}
+
+func (w *WAL[K, V]) compact(reopen bool) error {
+	if err := w.sync(); err != nil {
+		return err
+	}
+	if err := w.segment.close(); err != nil {
+		return fmt.Errorf("otter: close segment: %w", err)
+	}
+	w.segment = nil
+	last := w.seq
+	if reopen {
+		if err := w.openSegment(); err != nil {
+			return err
+		}
+	}
+
+	// every change that is not in the snapshot is recorded to the new segment.
+	err := SaveCacheToFile(w.cache, filepath.Join(w.dir, walSnapshotName), &SaveOptions[K, V]{
+		Codec:      w.codec,
+		Compressor: w.compressor,
+	})
+	if err != nil {
+		return err
+	}
+	w.lastCompaction = w.clock.NowNano()
+
+	seqs, err := w.segments()
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		if seq > last {
+			break
+		}
+		path := w.segmentPath(seq)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("otter: remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Close stops logging the changes of the cache and compacts the log into the final snapshot.
+//
+// Close returns the error of the final compaction. Subsequent calls return the same error.
+func (w *WAL[K, V]) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		<-w.stopped
+
+		// flush the pending changes to the WAL before detaching it.
+		w.cache.CleanUp()
+		w.cache.cache.wal.CompareAndSwap(w, nil)
+
+		w.fileMutex.Lock()
+		defer w.fileMutex.Unlock()
+		w.closeErr = w.compact(false)
+		w.closed = true
+		if w.segment != nil {
+			//nolint:errcheck // the compaction error is more important
+			w.segment.close()
+			w.segment = nil
+		}
+	})
+	return w.closeErr
+}
+
+// walSegmentWriter appends records to a log segment.
+type walSegmentWriter[K comparable, V any] struct {
+	file  *os.File
+	w     *bufio.Writer
+	enc   EntryEncoder[K, V]
+	frame bytes.Buffer
+	buf   []byte
+}
+
+func newWALSegmentWriter[K comparable, V any](file *os.File, codec Codec[K, V]) *walSegmentWriter[K, V] {
+	sw := &walSegmentWriter[K, V]{
+		file: file,
+		w:    bufio.NewWriter(file),
+		buf:  make([]byte, 0, 32),
+	}
+	sw.enc = codec.NewEncoder(&sw.frame)
+	return sw
+}
+
+func (sw *walSegmentWriter[K, V]) writeHeader(fingerprint uint64) error {
+	b := append(sw.buf[:0], walMagic...)
+	b = binary.BigEndian.AppendUint16(b, walVersion)
+	b = binary.BigEndian.AppendUint64(b, fingerprint)
+	if _, err := sw.w.Write(b); err != nil {
+		return err
+	}
+	return sw.sync()
+}
+
+func (sw *walSegmentWriter[K, V]) writeRecord(r walRecord[K, V]) error {
+	sw.frame.Reset()
+	sw.frame.WriteByte(byte(r.op))
+	if err := sw.enc.Encode(r.entry); err != nil {
+		return err
+	}
+	if sw.frame.Len() > maxFrameSize {
+		return fmt.Errorf("otter: too large entry: %d bytes", sw.frame.Len())
+	}
+
+	b := binary.AppendUvarint(sw.buf[:0], uint64(sw.frame.Len()))
+	if _, err := sw.w.Write(b); err != nil {
+		return err
+	}
+	if _, err := sw.w.Write(sw.frame.Bytes()); err != nil {
+		return err
+	}
+	_, err := sw.w.Write(binary.BigEndian.AppendUint32(sw.buf[:0], crc32.Checksum(sw.frame.Bytes(), crcTable)))
+	return err
+}
+
+func (sw *walSegmentWriter[K, V]) sync() error {
+	if err := sw.w.Flush(); err != nil {
+		return err
+	}
+	return sw.file.Sync()
+}
+
+func (sw *walSegmentWriter[K, V]) close() error {
+	if err := sw.sync(); err != nil {
+		//nolint:errcheck // the original error is more important
+		sw.file.Close()
+		return err
+	}
+	return sw.file.Close()
+}
+
+// walSegmentReader reads a log segment.
+//
+// walSegmentReader exposes the concatenation of the record payloads as an [io.Reader],
+// which returns [io.EOF] after the last valid record. The ops of the read records are queued,
+// so that they can be matched with the decoded entries.
+type walSegmentReader struct {
+	r       *bufio.Reader
+	buf     [32]byte
+	payload []byte
+	ops     []walOp
+	done    bool
+}
+
+func newWALSegmentReader(r io.Reader) *walSegmentReader {
+	return &walSegmentReader{
+		r: bufio.NewReader(r),
+	}
+}
+
+func (sr *walSegmentReader) readHeader(fingerprint uint64) error {
+	b := sr.buf[:len(walMagic)+2+8]
+	if _, err := io.ReadFull(sr.r, b); err != nil {
+		return corrupted(err)
+	}
+	if string(b[:len(walMagic)]) != walMagic {
+		return fmt.Errorf("%w: bad magic", ErrInvalidSnapshot)
+	}
+	b = b[len(walMagic):]
+	if version := binary.BigEndian.Uint16(b); version != walVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSnapshotVersion, version)
+	}
+	if binary.BigEndian.Uint64(b[2:]) != fingerprint {
+		return ErrSnapshotTypeMismatch
+	}
+	return nil
+}
+
+// nextOp returns the op of the oldest decoded record.
+func (sr *walSegmentReader) nextOp() walOp {
+	if len(sr.ops) == 0 {
+		return 0
+	}
+	op := sr.ops[0]
+	sr.ops = sr.ops[1:]
+	return op
+}
+
+// Read reads the record payloads.
+func (sr *walSegmentReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := sr.nextRecord(); err != nil {
+		return 0, err
+	}
+
+	n := copy(p, sr.payload)
+	sr.payload = sr.payload[n:]
+	return n, nil
+}
+
+// ReadByte reads a single byte of the record payloads.
+func (sr *walSegmentReader) ReadByte() (byte, error) {
+	if err := sr.nextRecord(); err != nil {
+		return 0, err
+	}
+
+	b := sr.payload[0]
+	sr.payload = sr.payload[1:]
+	return b, nil
+}
+
+// nextRecord reads the next valid record if the payload of the current one is consumed.
+func (sr *walSegmentReader) nextRecord() error {
+	for len(sr.payload) == 0 {
+		if sr.done {
+			return io.EOF
+		}
+
+		record, ok := sr.readRecord()
+		if !ok {
+			sr.done = true
+			return io.EOF
+		}
+		sr.ops = append(sr.ops, walOp(record[0]))
+		sr.payload = record[1:]
+	}
+	return nil
+}
+
+// readRecord reads the next record. It returns false if the record is incomplete or corrupted.
+func (sr *walSegmentReader) readRecord() ([]byte, bool) {
+	length, err := binary.ReadUvarint(sr.r)
+	if err != nil || length < 2 || length > maxFrameSize {
+		return nil, false
+	}
+
+	record := make([]byte, length+4)
+	if _, err := io.ReadFull(sr.r, record); err != nil {
+		return nil, false
+	}
+	record, checksum := record[:length], record[length:]
+	if crc32.Checksum(record, crcTable) != binary.BigEndian.Uint32(checksum) {
+		return nil, false
+	}
+	return record, true
+}
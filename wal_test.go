@@ -0,0 +1,400 @@
+// Copyright (c) 2025 Alexey Mayshev and contributors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otter
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recoverWAL recovers the cache from dir without attaching a WAL to it,
+// which simulates a restart after a crash.
+func recoverWAL[K comparable, V any](c *Cache[K, V], dir string) error {
+	w := &WAL[K, V]{
+		cache: c,
+		dir:   dir,
+		codec: GobCodec[K, V]{},
+	}
+	return w.recover()
+}
+
+func TestWAL(t *testing.T) {
+	t.Parallel()
+
+	for name, maximum := range map[string]int{
+		"bounded":   100,
+		"unbounded": 0,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			mc := newManualClock()
+			newCache := func() *Cache[int, int] {
+				return Must(&Options[int, int]{
+					MaximumSize: maximum,
+					Clock:       mc,
+					Logger:      &NoopLogger{},
+				})
+			}
+			recovered := func(t *testing.T) map[int]int {
+				t.Helper()
+
+				c := newCache()
+				require.NoError(t, recoverWAL(c, dir))
+				return maps.Collect(c.All())
+			}
+
+			c := newCache()
+			w, err := OpenWAL(c, dir)
+			require.NoError(t, err)
+			_, err = OpenWAL(c, t.TempDir())
+			require.Error(t, err)
+
+			want := make(map[int]int)
+			for i := 0; i < 10; i++ {
+				c.Set(i, i)
+				want[i] = i
+			}
+			c.Set(1, 100)
+			want[1] = 100
+			c.Invalidate(3)
+			delete(want, 3)
+			c.CleanUp()
+			require.NoError(t, w.Sync())
+			require.Equal(t, want, recovered(t))
+
+			require.NoError(t, w.Compact())
+			c.Set(10, 10)
+			want[10] = 10
+			c.CleanUp()
+			require.NoError(t, w.Sync())
+			require.Equal(t, want, recovered(t))
+
+			// a torn record at the end of the segment is ignored.
+			f, err := os.OpenFile(w.segmentPath(w.seq), os.O_WRONLY|os.O_APPEND, 0)
+			require.NoError(t, err)
+			_, err = f.Write([]byte{20, byte(walSet), 1, 2, 3})
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			require.Equal(t, want, recovered(t))
+
+			c.Invalidate(0)
+			delete(want, 0)
+			require.NoError(t, w.Close())
+			require.NoError(t, w.Close())
+			c.Set(11, 11)
+
+			files, err := filepath.Glob(filepath.Join(dir, walSegmentPrefix+"*"))
+			require.NoError(t, err)
+			require.Empty(t, files)
+			require.Equal(t, want, recovered(t))
+		})
+	}
+}
+
+func TestWAL_Expiration(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mc := newManualClock()
+	newCache := func() *Cache[int, int] {
+		return Must(&Options[int, int]{
+			ExpiryCalculator: ExpiryWriting[int, int](time.Hour),
+			Clock:            mc,
+			Logger:           &NoopLogger{},
+		})
+	}
+
+	c := newCache()
+	w, err := OpenWAL(c, dir)
+	require.NoError(t, err)
+
+	c.Set(1, 1)
+	c.Set(2, 2)
+	c.SetExpiresAfter(2, 2*time.Hour)
+	c.CleanUp()
+	require.NoError(t, w.Sync())
+
+	mc.advance(90 * time.Minute)
+	loaded := newCache()
+	require.NoError(t, recoverWAL(loaded, dir))
+
+	_, ok := loaded.GetIfPresent(1)
+	require.False(t, ok)
+	entry, ok := loaded.GetEntryQuietly(2)
+	require.True(t, ok)
+	require.Equal(t, 30*time.Minute, entry.ExpiresAfter())
+
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_ExpiredSetOverridesSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mc := newManualClock()
+	newCache := func() *Cache[int, int] {
+		return Must(&Options[int, int]{
+			ExpiryCalculator: ExpiryWritingFunc(func(entry Entry[int, int]) time.Duration {
+				if entry.Value > 1 {
+					return time.Minute
+				}
+				return time.Hour
+			}),
+			Clock:  mc,
+			Logger: &NoopLogger{},
+		})
+	}
+
+	c := newCache()
+	w, err := OpenWAL(c, dir)
+	require.NoError(t, err)
+
+	c.Set(1, 1)
+	c.CleanUp()
+	require.NoError(t, w.Compact())
+	c.Set(1, 2)
+	c.CleanUp()
+	require.NoError(t, w.Sync())
+
+	// the original cache expires the key, so the recovered one must not bring back the snapshot's value.
+	mc.advance(10 * time.Minute)
+	_, ok := c.GetIfPresent(1)
+	require.False(t, ok)
+
+	loaded := newCache()
+	require.NoError(t, recoverWAL(loaded, dir))
+	_, ok = loaded.GetIfPresent(1)
+	require.False(t, ok)
+
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_ConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	for name, maximum := range map[string]int{
+		"bounded":   1000,
+		"unbounded": 0,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			newCache := func() *Cache[int, int] {
+				return Must(&Options[int, int]{
+					MaximumSize: maximum,
+					Logger:      &NoopLogger{},
+				})
+			}
+
+			c := newCache()
+			w, err := OpenWAL(c, dir)
+			require.NoError(t, err)
+
+			const (
+				goroutines = 8
+				keys       = 2
+			)
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 5000; i++ {
+						key := i % keys
+						if (i+g)%3 == 0 {
+							c.Invalidate(key)
+						} else {
+							c.Set(key, g*10000+i)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			c.CleanUp()
+			require.NoError(t, w.Sync())
+
+			// the log records the changes of every key in the order they were applied.
+			loaded := newCache()
+			require.NoError(t, recoverWAL(loaded, dir))
+			require.Equal(t, maps.Collect(c.All()), maps.Collect(loaded.All()))
+
+			require.NoError(t, w.Close())
+		})
+	}
+}
+
+func TestWAL_ReorderedWriteTasks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	newCache := func() *Cache[int, int] {
+		return Must(&Options[int, int]{
+			MaximumSize: 100,
+			Logger:      &NoopLogger{},
+		})
+	}
+
+	c := newCache()
+	w, err := OpenWAL(c, dir)
+	require.NoError(t, err)
+	c.Set(1, 1)
+	c.CleanUp()
+
+	// the tasks of an invalidation and the following set of the key may be applied in the reverse order.
+	c.cache.evictionMutex.Lock()
+	c.Invalidate(1)
+	c.Set(1, 2)
+	deleteTask := c.cache.writeBuffer.TryPop()
+	addTask := c.cache.writeBuffer.TryPop()
+	require.Equal(t, deleteReason, deleteTask.writeReason)
+	require.Equal(t, addReason, addTask.writeReason)
+	c.cache.runTask(addTask)
+	c.cache.runTask(deleteTask)
+	c.cache.evictionMutex.Unlock()
+	require.NoError(t, w.Sync())
+
+	loaded := newCache()
+	require.NoError(t, recoverWAL(loaded, dir))
+	require.Equal(t, map[int]int{1: 2}, maps.Collect(loaded.All()))
+
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_RecoveredEntriesAreNotLogged(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	newCache := func() *Cache[int, int] {
+		return Must(&Options[int, int]{
+			MaximumSize: 100_000,
+			Logger:      &NoopLogger{},
+		})
+	}
+
+	c := newCache()
+	w, err := OpenWAL(c, dir)
+	require.NoError(t, err)
+	for i := 0; i < 50_000; i++ {
+		c.Set(i, i)
+	}
+	c.CleanUp()
+	require.NoError(t, w.Sync())
+
+	loaded := newCache()
+	lw, err := OpenWAL(loaded, dir)
+	require.NoError(t, err)
+	require.Equal(t, c.EstimatedSize(), loaded.EstimatedSize())
+	loaded.CleanUp()
+	require.NoError(t, lw.Sync())
+
+	// the new segment contains only the header.
+	info, err := os.Stat(lw.segmentPath(lw.seq))
+	require.NoError(t, err)
+	require.Equal(t, int64(len(walMagic)+2+8), info.Size())
+
+	require.NoError(t, lw.Close())
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_FailedCompaction(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	newCache := func() *Cache[int, int] {
+		return Must(&Options[int, int]{
+			Logger: &NoopLogger{},
+		})
+	}
+
+	c := newCache()
+	// the WAL is synced only manually.
+	w, err := OpenWAL(c, dir, &WALOptions[int, int]{
+		SyncInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	c.Set(1, 1)
+
+	// the next segment can't be created.
+	next := w.segmentPath(w.seq + 1)
+	require.NoError(t, os.Mkdir(next, 0o755))
+	require.Error(t, w.Compact())
+	require.Nil(t, w.segment)
+
+	// the records are not piled up while the segment can't be reopened.
+	c.Set(2, 2)
+	require.Error(t, w.Sync())
+	require.Empty(t, w.pending)
+
+	require.NoError(t, os.Remove(next))
+	c.Set(3, 3)
+	require.NoError(t, w.Sync())
+	require.NotNil(t, w.segment)
+	require.NoError(t, w.Compact())
+
+	loaded := newCache()
+	require.NoError(t, recoverWAL(loaded, dir))
+	require.Equal(t, map[int]int{1: 1, 2: 2, 3: 3}, maps.Collect(loaded.All()))
+
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_SetRefreshableAfter(t *testing.T) {
+	t.Parallel()
+
+	for name, maximum := range map[string]int{
+		"bounded":   100,
+		"unbounded": 0,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			mc := newManualClock()
+			newCache := func() *Cache[int, int] {
+				return Must(&Options[int, int]{
+					MaximumSize:       maximum,
+					RefreshCalculator: RefreshWriting[int, int](time.Hour),
+					Clock:             mc,
+					Logger:            &NoopLogger{},
+				})
+			}
+
+			c := newCache()
+			w, err := OpenWAL(c, dir)
+			require.NoError(t, err)
+			c.Set(1, 1)
+			c.SetRefreshableAfter(1, 2*time.Hour)
+			c.CleanUp()
+			require.NoError(t, w.Sync())
+
+			loaded := newCache()
+			require.NoError(t, recoverWAL(loaded, dir))
+			entry, ok := loaded.GetEntryQuietly(1)
+			require.True(t, ok)
+			require.Equal(t, 2*time.Hour, entry.RefreshableAfter())
+
+			require.NoError(t, w.Close())
+		})
+	}
+}